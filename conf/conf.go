@@ -41,6 +41,17 @@ type MailConf struct {
 	SMTPPort int
 	// Whether the IMAP protocol output should be logged or not
 	ImapLog bool
+	// Whether permanently deleting mails and emptying folders has to be confirmed by the user
+	ConfirmDelete bool
+	// Mails in the Trash and Junk folder older than the given number of days are deleted
+	// permanently, when the user logs in (0 = mails are kept until the folder is emptied)
+	TrashRetentionDays int
+	// The spam filters, whose header fields are used to classify mails, in the order of their
+	// priority: gmx, spamassassin, rspamd, microsoft (all filters are used, if none is given)
+	SpamProvider []string
+	// The authserv-ids of the servers (e.g., the own mail server), whose Authentication-Results:
+	// are trusted (only the topmost result of the mail is trusted, if none is given)
+	TrustedAuthServID []string
 }
 
 type WebConf struct {
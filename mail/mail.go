@@ -5,7 +5,6 @@ import (
 	"errors"
 	"fmt"
 	"github.com/mxk/go-imap/imap"
-	"github.com/scorredoira/email"
 	"io"
 	"log"
 	"mdrobek/watney/conf"
@@ -25,6 +24,10 @@ type MailCon struct {
 	mailbox string
 	// delimiter for the current imap server
 	delim string
+	// folder path -> server mailbox name, for all folders retrieved by ListFolders
+	folderNames map[string]string
+	// special-use attribute -> folder path, for all special-use folders announced by the server
+	specialFolders map[string]string
 	// configuration to be used to connect to the imap mail server
 	conf *conf.MailConf
 	// the logger to be used for the mail and imap package
@@ -34,7 +37,33 @@ type MailCon struct {
 	// Quit channel to end keep alive method
 	QuitChan chan struct{}
 	// Mutex to synchronize IMAP access
-	mutex *sync.Mutex
+	mutex *imapMutex
+	// the currently selected folder ("/" = root)
+	selectedFolder string
+	// the number of mails in the selected folder, as last reported by the server (EXISTS)
+	lastExists uint32
+	// the number of responses in the data queue, that have already been published as events
+	dispatched int
+	// the UIDVALIDITY and UIDNEXT of each folder, when it was last checked for new mails
+	uidStates uidTracker
+	// all registered event listeners (see AddEventListener)
+	listeners map[chan MailEvent]bool
+	// Mutex to synchronize access to the event listeners
+	listenerMutex sync.Mutex
+	// the folders with new mails, that haven't been loaded and published yet (see publishNewMails)
+	newMailFolders map[string]bool
+	// the listener collecting all events between two calls of PollEvents
+	pollListener chan MailEvent
+	// Mutex to synchronize access to the poll listener
+	pollMutex sync.Mutex
+	// the lower case addresses of all senders, whose mails may load remote content
+	trustedSenders map[string]bool
+	// Mutex to synchronize access to the trusted senders
+	trustMutex sync.Mutex
+	// the spam filters used to classify mails in the order of their priority
+	spamProviders []SpamProvider
+	// the authserv-ids of the servers, whose authentication results are trusted
+	authServIDs []string
 }
 
 type Mail struct {
@@ -47,6 +76,8 @@ type Mail struct {
 	// the content parts of the mail: Content-Type -> Part
 	// "text/plain" -> Part
 	Content Content
+	// the MIME part tree of the mail including all attachments (only set, if the content is loaded)
+	Parts *MailPart
 }
 
 type MailInformation string
@@ -68,12 +99,31 @@ type Header struct {
 	Subject string
 	// sender of the mail (From:)
 	Sender string
-	// receiver address of this mail (To:)
-	// TODO: Receiver can be multiple mail addresses!
+	// receiver addresses of this mail as given in the header (To:), see To for the parsed list
 	Receiver string
+	// the parsed senders of the mail (From:)
+	From []Address
+	// the parsed receivers of the mail (To:)
+	To []Address
+	// the parsed carbon copy receivers of the mail (Cc:)
+	Cc []Address
+	// the parsed blind carbon copy receivers (Bcc:), only known for mails written by the user
+	Bcc []Address
+	// the addresses replies to this mail should be sent to (Reply-To:)
+	ReplyTo []Address
 	// Whether the mail has been classified as spam and if so, with what degree
-	// -1 - not been analysed | 0 - no spam | >0 - classified as spam (number tells the tool used)
+	// -1 - not been analysed | 0 - no spam | >0 - classified as spam (normalized score 1 - 10)
 	SpamIndicator int
+	// the name of the spam filter, that classified the mail (empty, if not classified)
+	SpamProvider string
+	// the unique ID of the mail including angle brackets (Message-ID:)
+	MessageID string
+	// the ID of the mail this mail is a reply to (In-Reply-To:)
+	InReplyTo string
+	// the IDs of all preceding mails of the discussion, oldest first (References:)
+	References []string
+	// the SPF, DKIM and DMARC results of the mail and the trust level derived from them
+	Authenticity Authenticity
 	// The parsed MIMEHeader
 	MimeHeader PMIMEHeader
 }
@@ -88,6 +138,8 @@ type PMIMEHeader struct {
 	Encoding string
 	// Boundary used in case of a multipart Content-Type
 	MultipartBoundary string
+	// The charset of a (non-multipart) text body, e.g., ISO-8859-1 (stored in Content-Type)
+	Charset string
 }
 
 // the content parts of the mail: Content-Type -> Part
@@ -102,6 +154,8 @@ type ContentPart struct {
 	Encoding string
 	// The body of that part
 	Body string
+	// Whether remote content (e.g., images) has been removed from the body (see SanitizeHTML)
+	RemoteContentBlocked bool
 }
 
 // Holds the following information of a mail: Seen, Deleted, Answered
@@ -118,6 +172,9 @@ type Flags struct {
 	Draft bool `flag: "\\Draft"`
 	// Message is "recently" arrived in this mailbox.
 	Recent bool `flag: "\\Recent"`
+	// All keywords of the mail, which aren't system flags, e.g., $Forwarded, $Junk or labels set
+	// by other clients like $label1 (sorted)
+	Keywords []string
 }
 
 // Used to switch between the IMAP fetch used to retrieve mails
@@ -149,7 +206,8 @@ func NewMailCon(conf *conf.MailConf) (newMC *MailCon, err error) {
 	newMC = new(MailCon)
 	newMC.conf = conf
 	newMC.mailbox = DFLT_MAILBOX_NAME
-	newMC.mutex = &sync.Mutex{}
+	newMC.mutex = &imapMutex{}
+	newMC.uidStates = make(uidTracker)
 	// Check if the given configuration is valid
 	if confOK, err := newMC.checkConf(); !confOK {
 		return newMC, err
@@ -173,7 +231,8 @@ func NewMailCon(conf *conf.MailConf) (newMC *MailCon, err error) {
 		defer newMC.Close()
 		return newMC, err
 	}
-	// Schedule a NOOP keep-alive request every 30 seconds to keep the IMAP connection alive
+	// Schedule a keep-alive request (IDLE or NOOP) every 10 seconds to keep the IMAP connection
+	// alive and to receive updates from the server
 	newMC.keepAlive(10)
 	// Return the new established connection
 	return newMC, nil
@@ -200,8 +259,13 @@ func (mc *MailCon) Authenticate(username, password string) (*MailCon, error) {
 	} else {
 		mc.delim = cmd.Data[0].MailboxInfo().Delim
 	}
+	// Detect the folders for sent, trashed, ... mails (special-use folders)
+	if _, err = mc.listFolders_internal(); err != nil {
+		// ... we couldn't retrieve them, the default folder names are used instead
+		mc.logMC(fmt.Sprintf("Couldn't detect special-use folders: %s", err.Error()), imap.LogAll)
+	}
 	// Clean the data queue
-	mc.client.Data = nil
+	mc.clearData()
 	// Set the client in the NO_OP state to continuously receive updates from the server
 	if _, err = mc.waitFor(mc.client.Noop()); err != nil {
 		return mc, err
@@ -228,6 +292,7 @@ func (mc *MailCon) Close() error {
 		close(mc.QuitChan)
 		_, err = mc.waitFor(mc.client.Logout(30 * time.Second))
 	}
+	mc.removeAllEventListeners()
 	return err
 }
 
@@ -372,6 +437,10 @@ func (mc *MailCon) UpdateMailFlags(folder, uid string, f *Flags, add bool) error
 	} else {
 		task = "-"
 	}
+	// 3) Custom keywords can only be stored, if the folder allows it (PERMANENTFLAGS)
+	if err := mc.checkKeywords_internal(f); err != nil {
+		return err
+	}
 	set, _ := imap.NewSeqSet(uid)
 	_, err := mc.waitFor(mc.client.UIDStore(set, strings.Replace("*FLAGS", "*", task, 1),
 		SerializeFlags(f)))
@@ -380,20 +449,20 @@ func (mc *MailCon) UpdateMailFlags(folder, uid string, f *Flags, add bool) error
 
 /**
  * This method moves the mail associated with the given 'UID', from the folder where it currently
- * resides, into the "Trash" folder and sets its "Deleted" flag.
+ * resides, into the "Trash" folder (the \Trash special-use folder).
  * After this operation, the following post condition holds, if a mail with the given UID existed
  * in the original folder:
- *  - The mail in the original folder has its "Deleted" flag set
+ *  - The mail has been removed from the original folder
  *  - The folder 'Trash' now contains a new mail with the Header and Content of the original
  *    mail (but with a new UID)
  *
  * ATTENTION:
- * The original mail is NOT deleted from the folder where it resided (only its deleted flag is set).
- * The deletion operation will happen when the IMAP connection is closed, or the EXPUNGE operation
- * is called.
+ * If the server supports neither MOVE nor UIDPLUS, the original mail is NOT deleted from the
+ * folder where it resided (only its deleted flag is set). The deletion operation will happen when
+ * the IMAP connection is closed, or the EXPUNGE operation is called (see Expunge).
  */
 func (mc *MailCon) TrashMail(uid, origFolder string) (uint32, error) {
-	return mc.MoveMail(uid, origFolder, "Trash")
+	return mc.MoveMail(uid, origFolder, mc.SpecialFolder(SPECIAL_USE_TRASH))
 }
 
 /**
@@ -401,14 +470,14 @@ func (mc *MailCon) TrashMail(uid, origFolder string) (uint32, error) {
  * resides, into the 'targetFolder'.
  * After this operation, the following post condition holds, if a mail with the given UID existed
  * in the original folder:
- *  - The mail in the original folder has its "Deleted" flag set
+ *  - The mail has been removed from the 'origFolder'
  *  - The folder 'targetFolder' now contains a new mail with the Header and Content of the original
  *    mail (but with a new UID)
  *
  * ATTENTION:
- * The original mail is NOT deleted from the 'origFolder' where it resided (only its deleted flag
- * is set). The deletion operation will happen when the IMAP connection is closed, or the EXPUNGE
- * operation is called.
+ * If the server supports neither MOVE nor UIDPLUS, the original mail is NOT deleted from the
+ * 'origFolder' where it resided (only its deleted flag is set). The deletion operation will happen
+ * when the IMAP connection is closed, or the EXPUNGE operation is called (see Expunge).
  */
 func (mc *MailCon) MoveMail(uid, origFolder, targetFolder string) (uint32, error) {
 	mc.mutex.Lock()
@@ -416,71 +485,6 @@ func (mc *MailCon) MoveMail(uid, origFolder, targetFolder string) (uint32, error
 	return mc.moveMail_internal(uid, origFolder, targetFolder)
 }
 
-/**
- * Response: * 25 EXISTS | 2 | 25
- * new Data update: * 25 EXISTS
- * This is all the fields we get: %!s(uint32=25)
- * This is all the fields we get: EXISTS
- * Response: * 1 RECENT | 2 | 1
- * new Data update: * 1 RECENT
- * This is all the fields we get: %!s(uint32=1)
- * This is all the fields we get: RECENT
- *
- * Usually, a new mail update from the server splits into 2 responses: 1 EXIST and 1 RECENT
- * The EXIST command provides the server UID of the new message received and the RECENT command
- * tells the client how many new messages have been recently received.
- *
- * @return Array of sequence numbers for all newly received mails
- */
-func (mc *MailCon) CheckNewMails() ([]uint32, error) {
-	mc.mutex.Lock()
-	defer mc.mutex.Unlock()
-	var (
-		recentMails       uint32
-		lastSeqNumber     uint32
-		newMailSeqNumbers       = make([]uint32, 0)
-		err               error = nil
-	)
-	if mc.client.Data != nil && len(mc.client.Data) > 0 {
-		for _, resp := range mc.client.Data {
-			fmt.Printf("Response: %s | %d | %d\n", resp.String(), resp.Type, resp.Value())
-			if resp.Type == imap.Data {
-				fmt.Printf("new Data update: %s | Label: %s\n", resp.String(), resp.Label)
-				f := resp.Fields
-				if len(f) > 1 {
-					// The response is either an EXIST or a RECENT
-					//			   Fields[0]	  |			   Fields[1]
-					// ---------------------------|-------------------------------
-					// last sequence number : int |				EXIST
-					// number of new mails  : int |				RECENT
-					switch n := imap.AsNumber(f[0]); strings.ToUpper(imap.AsAtom(f[1])) {
-					case "RECENT":
-						recentMails = n
-					case "EXISTS":
-						lastSeqNumber = n
-					}
-				} else {
-					err = errors.New(fmt.Sprintf("Got a data update message with less than 2 "+
-						"fields: %s", resp.Fields))
-					fmt.Printf("[watney]: ERROR: %s\n", err.Error())
-				}
-			} else {
-				err = errors.New(fmt.Sprintf("Unhandled response in message queue, while "+
-					"checking for new mails: \n\t%s", resp.String()))
-			}
-		}
-	}
-	//	fmt.Printf("Received info is: %b, %s\n", recentMsg, newMsgUIDs)
-	// Empty the response message queue
-	mc.client.Data = nil
-	// Compute sequence numbers for all new mails
-	var i uint32
-	for i = 0; i < recentMails; i++ {
-		newMailSeqNumbers = append(newMailSeqNumbers, lastSeqNumber-i)
-	}
-	return newMailSeqNumbers, err
-}
-
 /**
  * Creates a new mail on the IMAP server with the given header information, flags and content
  * (body).
@@ -492,29 +496,54 @@ func (mc *MailCon) AddMailToFolder(h *Header, f *Flags, content string) (uint32,
 	return mc.createMailInFolder_internal(h, f, content)
 }
 
-func (mc *MailCon) SendMail(a smtp.Auth, from string, to []string, subject string,
-	body string) (err error) {
+/**
+ * Sends the given mail via SMTP and appends the very same MIME message to the 'Sent' folder, so
+ * the stored copy matches what has been delivered. Bcc receivers are thereby not contained in
+ * either message.
+ */
+func (mc *MailCon) SendMail(a smtp.Auth, m *OutgoingMail) (err error) {
 	mc.mutex.Lock()
 	defer mc.mutex.Unlock()
-	// 1) Create new SMTP message and send it
-	m := email.NewMessage(subject, body)
-	m.From = from
-	m.To = to
+	var (
+		msg        []byte
+		from       Address
+		recipients []string
+	)
+	// 1) Build the MIME message and send it to all receivers (including Bcc)
+	if msg, err = BuildMessage(m); err != nil {
+		return err
+	}
+	if from, err = m.sender(); err != nil {
+		return err
+	}
+	if recipients, err = m.Recipients(); err != nil {
+		return err
+	}
 	if mc.conf.SkipCertificateVerification {
-		err = mc.sendMailSkipCert(a, from, m.Tolist(), m.Bytes())
+		err = mc.sendMailSkipCert(a, from.Address, recipients, msg)
 	} else {
-		err = email.Send(fmt.Sprintf("%s:%d", mc.conf.SMTPAddress, mc.conf.SMTPPort), a, m)
+		err = smtp.SendMail(fmt.Sprintf("%s:%d", mc.conf.SMTPAddress, mc.conf.SMTPPort), a,
+			from.Address, recipients, msg)
 	}
-	// 2) If that worked well, add this mail to the 'Sent' folder of the users inbox
+	// 2) If that worked well, add this mail to the 'Sent' folder (\Sent special-use folder)
 	if nil == err {
 		// Todo: Think about having this in its own go-routine -> how to handle a possible error?
-		_, err = mc.createMailInFolder_internal(&Header{
-			Date:     time.Now(),
-			Subject:  subject,
-			Sender:   from,
-			Receiver: strings.Join(to, ", "),
-			Folder:   "Sent",
-		}, &Flags{Seen: true}, body)
+		_, err = mc.appendMessage_internal(mc.specialFolder(SPECIAL_USE_SENT), &Flags{Seen: true},
+			m.Date, msg)
+	}
+	// 3) Mark the original mail of a reply or forward (the mail has been sent anyway => only log)
+	if nil == err && nil != m.Origin {
+		if flagErr := mc.markOrigin_internal(m.Origin); flagErr != nil {
+			mc.logMC(fmt.Sprintf("Couldn't flag original mail %d in folder '%s': %s", m.Origin.UID,
+				m.Origin.Folder, flagErr.Error()), imap.LogAll)
+		}
+	}
+	// 4) The draft of the mail isn't needed anymore, once the mail has been delivered
+	if nil == err && m.DraftUID > 0 {
+		if draftErr := mc.removeDraft_internal(m.DraftUID); draftErr != nil {
+			mc.logMC(fmt.Sprintf("Couldn't remove draft %d: %s", m.DraftUID, draftErr.Error()),
+				imap.LogAll)
+		}
 	}
 	return err
 }
@@ -538,6 +567,7 @@ func (mc *MailCon) loadMails(set *imap.SeqSet, folder string, withContent bool,
 			"RFC822.HEADER"}
 		mails       []Mail = []Mail{}
 		mailContent Content
+		mailParts   *MailPart
 		err         error
 	)
 	if withContent {
@@ -550,7 +580,8 @@ func (mc *MailCon) loadMails(set *imap.SeqSet, folder string, withContent bool,
 		// 3) Transform the retrieved messages into mails with headers
 		for _, resp := range cmd.Data {
 			// a) Parse the Header
-			mailHeader, err := parseHeader(resp.MessageInfo())
+			mailHeader, err := parseHeader(resp.MessageInfo(), mc.spamProviders,
+				mc.authServIDs)
 			if nil != err {
 				mc.Logger.Printf("Couldn't parse header of mail\n Original error: %s", err.Error())
 			}
@@ -559,7 +590,8 @@ func (mc *MailCon) loadMails(set *imap.SeqSet, folder string, withContent bool,
 			flags := readFlags(resp.MessageInfo())
 			// c) Read the content if requested
 			if withContent {
-				mailContent, err = parseContent(resp.MessageInfo(), mailHeader.MimeHeader)
+				mailContent, mailParts, err = parseContent(resp.MessageInfo(),
+					mailHeader.MimeHeader)
 				if nil == err {
 					decodeContent(mailContent)
 				}
@@ -570,10 +602,11 @@ func (mc *MailCon) loadMails(set *imap.SeqSet, folder string, withContent bool,
 				Header:  mailHeader,
 				Flags:   flags,
 				Content: mailContent,
+				Parts:   mailParts,
 			})
 		}
 		// 4) Clean the data queue
-		mc.client.Data = nil
+		mc.clearData()
 		return mails, err
 	}
 }
@@ -585,17 +618,27 @@ func (mc *MailCon) loadMails(set *imap.SeqSet, folder string, withContent bool,
  */
 func (mc *MailCon) createMailInFolder_internal(h *Header, f *Flags,
 	content string) (uid uint32, err error) {
+	// Create the msg:
+	// Header info + empty line + content + empty line
+	var msg string = strings.Join([]string{SerializeHeader(h), "", content, ""}, "\r\n")
+	return mc.appendMessage_internal(h.Folder, f, h.Date, []byte(msg))
+}
+
+/**
+ * Appends the given raw MIME message to the given folder.
+ * ATTENTION: DOES NOT LOCK THE IMAP CONNECTION! => Has to be wrapped into a mutex lock method
+ * @return The UID of the new mail
+ */
+func (mc *MailCon) appendMessage_internal(folder string, f *Flags, date time.Time,
+	msg []byte) (uid uint32, err error) {
 	var (
-		// Create the msg:
-		// Header info + empty line + content + empty line
-		msg  string       = strings.Join([]string{SerializeHeader(h), "", content, ""}, "\r\n")
-		lit  imap.Literal = imap.NewLiteral([]byte(msg))
-		mbox string       = fmt.Sprintf("%s%s%s", mc.mailbox, mc.delim, h.Folder)
+		lit  imap.Literal = imap.NewLiteral(msg)
+		mbox string       = mc.mailboxName(folder)
 		cmd  *imap.Command
 		resp *imap.Response
 	)
 	// 1) Execute the actual append mail command
-	if cmd, err = mc.client.Append(mbox, imap.AsFlagSet(SerializeFlags(f)), &h.Date, lit); err != nil {
+	if cmd, err = mc.client.Append(mbox, imap.AsFlagSet(SerializeFlags(f)), &date, lit); err != nil {
 		return 0, err
 	}
 	if resp, err = cmd.Result(imap.OK); err != nil {
@@ -610,17 +653,7 @@ func (mc *MailCon) createMailInFolder_internal(h *Header, f *Flags,
 
 /**
  * This method moves the mail associated with the given 'UID', from the folder where it currently
- * resides, into the "toFolder" folder and sets its "Deleted" flag.
- * After this operation, the following post condition holds, if a mail with the given UID existed
- * in the original folder:
- *  - The mail in the original folder has its "Deleted" flag set
- *  - The folder 'newFolder' now contains a new mail with the Header and Content of the original
- *    mail (but with a new UID)
- *
- * ATTENTION:
- * The original mail is NOT deleted from the folder where it resided (only its deleted flag is set).
- * The deletion operation will happen when the IMAP connection is closed, or the EXPUNGE operation
- * is called.
+ * resides, into the "toFolder" folder (see moveMails_internal).
  *
  * @param uid The UID of the mail to be moved
  * @param folder The folder in which the current mail resides (its source location)
@@ -629,37 +662,182 @@ func (mc *MailCon) createMailInFolder_internal(h *Header, f *Flags,
  *		   went wrong.
  */
 func (mc *MailCon) moveMail_internal(uid, folder, toFolder string) (uint32, error) {
-	var targetMbox string = mc.mailbox
-	// 1) First check if we need to select a specific folder in the mailbox or if it is root
-	if err := mc.selectFolder(folder, true); err != nil {
+	origUID, err := strconv.ParseUint(uid, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("Given UID '%s' is not a valid ID", uid)
+	}
+	set := new(imap.SeqSet)
+	set.AddNum(uint32(origUID))
+	newUIDs, err := mc.moveMails_internal(set, folder, toFolder)
+	if err != nil {
 		return 0, err
 	}
-	// 2) Assign necessary variables and initiate IMAP Copy process
-	set, _ := imap.NewSeqSet(uid)
-	if len(toFolder) > 0 && toFolder != "/" {
-		targetMbox = fmt.Sprintf("%s%s%s", mc.mailbox, mc.delim, toFolder)
+	// The new UID is only known, if the server reported it (COPYUID)
+	if newUID, ok := newUIDs[uint32(origUID)]; ok {
+		return newUID, nil
 	}
-	cmd, err := mc.client.UIDCopy(set, targetMbox)
-	var resp *imap.Response
-	if resp, err = cmd.Result(imap.OK); err != nil {
-		return 0,
-			fmt.Errorf("[watney] ERROR waiting for result of copy command\n\t%s\n", err.Error())
-	}
-	// 3) Execute the copy process to copy the mail internally to the new folder
-	if _, err := mc.waitFor(mc.client.UIDStore(set, "+FLAGS",
-		SerializeFlags(&Flags{Deleted: true}))); err != nil {
-		return 0, fmt.Errorf("[watney] ERROR waiting for result of update flags command\n\t%s\n",
-			err.Error())
-	}
-	// 4) Check if the copy worked, and if so, return the new UID and no error
-	// The Response is an 'COPYUID' with the fields:
-	//  [0] COPYUID:string | [1] internaldate:long64 | [2] Orig-UID:uint32 | [3] New-UID:uint32
-	//  The Orig-UID resambles the given UID for the original mail
-	//  The New-UID is the UID of the new mail stored in the 'toFolder'
-	if len(resp.Fields) == 4 {
-		return imap.AsNumber(resp.Fields[3]), nil
+	return 0, errors.New("[watney] WARNING: Copy completed without doing anyting\n")
+}
+
+/**
+ * Moves the given mails from 'folder' into 'toFolder'. If the server supports the MOVE extension
+ * (RFC 6851), the mails are moved atomically (UID MOVE). Otherwise they are copied, flagged as
+ * deleted and removed from the source folder with UID EXPUNGE.
+ *
+ * ATTENTION:
+ * If the server supports neither MOVE nor UIDPLUS, the original mails are NOT deleted from the
+ * folder where they resided (only their deleted flag is set). The deletion operation will happen
+ * when the IMAP connection is closed, or the EXPUNGE operation is called.
+ *
+ * @param set The UIDs of the mails to be moved
+ * @return Original UID -> UID of the mail in the target folder (empty, if the server doesn't
+ *		   report the new UIDs, i.e., it doesn't support UIDPLUS)
+ */
+func (mc *MailCon) moveMails_internal(set *imap.SeqSet, folder, toFolder string) (map[uint32]uint32,
+	error) {
+	// 1) First check if we need to select a specific folder in the mailbox or if it is root
+	if err := mc.selectFolder(folder, false); err != nil {
+		return nil, err
+	}
+	var (
+		mbox imap.Field = mc.client.Quote(mc.mailboxName(toFolder))
+		cmd  *imap.Command
+		resp *imap.Response
+		err  error
+	)
+	// 2a) Move the mails atomically, if the server supports it
+	if mc.client.Caps["MOVE"] {
+		if cmd, err = mc.client.Send("UID MOVE", set, mbox); err != nil {
+			return nil, err
+		}
+		if resp, err = cmd.Result(imap.OK); err != nil {
+			return nil,
+				fmt.Errorf("[watney] ERROR waiting for result of move command\n\t%s\n", err.Error())
+		}
+		// The server reports the removed source mails as EXPUNGE responses
+		mc.publishExpunges(cmd)
 	} else {
-		return 0, errors.New("[watney] WARNING: Copy completed without doing anyting\n")
+		// 2b) Otherwise copy the mails to the new folder ...
+		if cmd, err = mc.client.UIDCopy(set, mc.mailboxName(toFolder)); err != nil {
+			return nil, err
+		}
+		if resp, err = cmd.Result(imap.OK); err != nil {
+			return nil,
+				fmt.Errorf("[watney] ERROR waiting for result of copy command\n\t%s\n", err.Error())
+		}
+		// ... and delete them in the original folder
+		if _, err := mc.waitFor(mc.client.UIDStore(set, "+FLAGS.SILENT",
+			SerializeFlags(&Flags{Deleted: true}))); err != nil {
+			return nil, fmt.Errorf(
+				"[watney] ERROR waiting for result of update flags command\n\t%s\n", err.Error())
+		}
+		if mc.client.Caps["UIDPLUS"] {
+			if _, err := mc.expunge_internal(set); err != nil {
+				return nil, err
+			}
+		}
+	}
+	// 3) The new UIDs are given by the COPYUID response code, which is part of the tagged OK of
+	//	  UID COPY, but is sent as untagged OK before the EXPUNGE responses for UID MOVE
+	if newUIDs, ok := parseCopyUID(resp); ok {
+		return newUIDs, nil
+	}
+	for _, resp := range append(append([]*imap.Response{}, cmd.Data...), mc.client.Data...) {
+		if newUIDs, ok := parseCopyUID(resp); ok {
+			return newUIDs, nil
+		}
+	}
+	return map[uint32]uint32{}, nil
+}
+
+/**
+ * Parses the given COPYUID response code (RFC 4315), e.g.:
+ *	"OK [COPYUID 38505 304,319:320 3956:3958] Done" -> {304: 3956, 319: 3957, 320: 3958}
+ * The fields of the response are:
+ *  [0] COPYUID:string | [1] UIDValidity:uint32 | [2] Orig-UIDs:set | [3] New-UIDs:set
+ * @return The original UIDs mapped to the new UIDs and false, if the response isn't a COPYUID
+ */
+func parseCopyUID(resp *imap.Response) (map[uint32]uint32, bool) {
+	if nil == resp || strings.ToUpper(resp.Label) != "COPYUID" || len(resp.Fields) != 4 {
+		return nil, false
+	}
+	var (
+		origUIDs []uint32          = expandUIDs(resp.Fields[2])
+		newUIDs  []uint32          = expandUIDs(resp.Fields[3])
+		uids     map[uint32]uint32 = map[uint32]uint32{}
+	)
+	if len(origUIDs) != len(newUIDs) {
+		return nil, false
+	}
+	for i, origUID := range origUIDs {
+		uids[origUID] = newUIDs[i]
+	}
+	return uids, true
+}
+
+/**
+ * Expands the given UID set field into its UIDs, e.g., "304,320:319" -> [304, 319, 320]. Single
+ * UIDs are parsed as numbers by the imap package, sets as atoms.
+ * @return The UIDs or an empty list, if the set is invalid (e.g., contains 0 or "*")
+ */
+func expandUIDs(f imap.Field) []uint32 {
+	var uids []uint32 = []uint32{}
+	switch value := f.(type) {
+	case uint32:
+		return append(uids, value)
+	case string:
+		for _, entry := range strings.Split(value, ",") {
+			bounds := strings.SplitN(entry, ":", 2)
+			from, err := strconv.ParseUint(bounds[0], 10, 32)
+			if err != nil {
+				return []uint32{}
+			}
+			to := from
+			if 2 == len(bounds) {
+				if to, err = strconv.ParseUint(bounds[1], 10, 32); err != nil {
+					return []uint32{}
+				}
+			}
+			// Ranges can be given in both directions (RFC 3501: "4:2" = "2:4") and the server
+			// sorts both sets in ascending order to map them (RFC 4315, 4.3)
+			if from > to {
+				from, to = to, from
+			}
+			if 0 == from {
+				return []uint32{}
+			}
+			for uid := from; uid <= to; uid++ {
+				uids = append(uids, uint32(uid))
+			}
+		}
+	}
+	return uids
+}
+
+/**
+ * Registers the commands of IMAP extensions, which aren't known to the imap package, so they can
+ * be sent with imap.Client.Send. The filter assigns the untagged responses of the server to the
+ * command (all other responses end up in imap.Client.Data).
+ */
+func registerCommands(c *imap.Client) {
+	// UID MOVE (RFC 6851) reports the moved mails as EXPUNGE responses
+	c.CommandConfig["UID MOVE"] = &imap.CommandConfig{
+		States: imap.Selected,
+		Filter: imap.LabelFilter("EXPUNGE"),
+	}
+	// XLIST (Gmail) returns the folders with their special-use attributes as XLIST responses
+	c.CommandConfig["XLIST"] = &imap.CommandConfig{
+		States: imap.Auth | imap.Selected,
+		Filter: imap.LabelFilter("XLIST"),
+	}
+	// UID SORT and UID THREAD (RFC 5256) return the UIDs in a single SORT/THREAD response
+	c.CommandConfig["UID SORT"] = &imap.CommandConfig{
+		States: imap.Selected,
+		Filter: imap.LabelFilter("SORT"),
+	}
+	c.CommandConfig["UID THREAD"] = &imap.CommandConfig{
+		States: imap.Selected,
+		Filter: imap.LabelFilter("THREAD"),
 	}
 }
 
@@ -675,6 +853,7 @@ func (mc *MailCon) dial() (c *imap.Client, err error) {
 	if err != nil {
 		return nil, err
 	}
+	registerCommands(c)
 	// Check for STARTTLS and use appropriate method and config object if need be
 	if c.Caps["STARTTLS"] {
 		_, err = mc.waitFor(c.StartTLS(&tls.Config{
@@ -689,15 +868,27 @@ func (mc *MailCon) dial() (c *imap.Client, err error) {
 }
 
 func (mc *MailCon) selectFolder(folder string, readonly bool) error {
-	var mailboxFolder string = mc.mailbox
-	if len(folder) > 0 && folder != "/" {
-		mailboxFolder = fmt.Sprintf("%s%s%s", mc.mailbox, mc.delim, folder)
-	}
-	if _, err := mc.waitFor(mc.client.Select(mailboxFolder, false)); err != nil {
+	// Publish all pending updates of the previously selected folder
+	mc.dispatchUpdates()
+	if _, err := mc.waitFor(mc.client.Select(mc.mailboxName(folder), false)); err != nil {
 		return err
 	}
 	// Clean client response queue
 	mc.client.Data = nil
+	mc.dispatched = 0
+	// Remember the selected folder to assign server updates (events) to it
+	if 0 == len(folder) {
+		folder = "/"
+	}
+	mc.selectedFolder = folder
+	if nil != mc.client.Mailbox {
+		mc.lastExists = mc.client.Mailbox.Messages
+		// Start tracking new mails of this folder (see loadNewMails_internal)
+		mc.uidStates.track(folder, FolderState{
+			UIDValidity: mc.client.Mailbox.UIDValidity,
+			UIDNext:     mc.client.Mailbox.UIDNext,
+		})
+	}
 	return nil
 }
 
@@ -751,6 +942,11 @@ func (mc *MailCon) checkConf() (bool, error) {
 	if 0 == len(mc.conf.Hostname) || mc.conf.Port < 1 {
 		return false, errors.New("Missing server address or username or password")
 	}
+	var err error
+	if mc.spamProviders, err = newSpamProviders(mc.conf.SpamProvider); err != nil {
+		return false, err
+	}
+	mc.authServIDs = mc.conf.TrustedAuthServID
 	// Set a default logger to hell, if none has been given
 	//	if nil == conf.logger {
 	//		conf.logger = log.New(os.DevNull, "", 0)
@@ -765,11 +961,16 @@ func (mc *MailCon) keepAlive(every int) {
 		for {
 			select {
 			case <-ticker.C:
-				// Send Noop to keep connection alive and receive new updates from the server
 				mc.mutex.Lock()
-				if _, err := mc.waitFor(mc.client.Noop()); err != nil {
+				if mc.client.Caps["IDLE"] && mc.IsAuthenticated() {
+					// Wait for updates from the server, until the connection is needed elsewhere
+					mc.idle()
+				} else if _, err := mc.waitFor(mc.client.Noop()); err != nil {
+					// Send Noop to keep connection alive and receive new updates from the server
 					mc.logMC(err.Error(), imap.LogAll)
 				}
+				mc.dispatchUpdates()
+				mc.publishNewMails()
 				mc.mutex.Unlock()
 			case <-mc.QuitChan:
 				ticker.Stop()
@@ -0,0 +1,90 @@
+package mail
+
+import (
+	"fmt"
+	netmail "net/mail"
+	"net/textproto"
+	"strings"
+)
+
+// A parsed mail address, e.g., "Mark Watney <mark@mars.com>"
+type Address struct {
+	// The display name (empty, if not given)
+	Name string
+	// The actual mail address, e.g., mark@mars.com
+	Address string
+}
+
+/**
+ * @return The address in the format of a mail header, e.g., "Mark Watney" <mark@mars.com>
+ *		   (non-ASCII names are encoded)
+ */
+func (a Address) String() string {
+	return (&netmail.Address{Name: a.Name, Address: a.Address}).String()
+}
+
+/**
+ * Parses the given recipients into addresses. Each recipient can be a single address or a comma
+ * separated list of addresses, e.g.: ["mark@mars.com, Melissa <melissa@hermes.com>", "rich@nasa"]
+ * @return An error, if one of the recipients isn't a valid address
+ */
+func ParseAddresses(recipients []string) ([]Address, error) {
+	var addresses []Address = []Address{}
+	for _, recipient := range recipients {
+		if 0 == len(strings.TrimSpace(recipient)) {
+			continue
+		}
+		list, err := netmail.ParseAddressList(recipient)
+		if err != nil {
+			return addresses, fmt.Errorf("Invalid mail address '%s': %s", recipient, err.Error())
+		}
+		for _, addr := range list {
+			addresses = append(addresses, Address{Name: addr.Name, Address: addr.Address})
+		}
+	}
+	return addresses, nil
+}
+
+/**
+ * Parses the addresses of the given header field (To, Cc, Bcc, Reply-To, From). Invalid addresses
+ * of broken mails are kept as they are, as long as they contain an '@'.
+ * @return nil, if the header field is missing
+ */
+func parseAddressHeader(rawMimeHeader textproto.MIMEHeader, target string) []Address {
+	values, ok := rawMimeHeader[target]
+	if !ok {
+		return nil
+	}
+	var (
+		addresses []Address              = []Address{}
+		parser    *netmail.AddressParser = &netmail.AddressParser{WordDecoder: newWordDecoder()}
+	)
+	if list, err := parser.ParseList(strings.Join(values, ", ")); err == nil {
+		for _, addr := range list {
+			addresses = append(addresses, Address{Name: addr.Name, Address: addr.Address})
+		}
+		return addresses
+	}
+	// Fall back to parsing each address on its own
+	for _, value := range values {
+		for _, entry := range strings.Split(value, ",") {
+			if addr, err := parser.Parse(entry); err == nil {
+				addresses = append(addresses, Address{Name: addr.Name, Address: addr.Address})
+			} else if entry = strings.TrimSpace(entry); strings.Contains(entry, "@") {
+				addresses = append(addresses, Address{Address: strings.Trim(entry, "<>")})
+			}
+		}
+	}
+	return addresses
+}
+
+/**
+ * @return The given addresses as value of a mail header field, e.g., "a@b.de, Name <c@d.de>"
+ */
+func formatAddresses(addresses []Address) string {
+	var formatted []string = make([]string, 0, len(addresses))
+	for _, addr := range addresses {
+		formatted = append(formatted, addr.String())
+	}
+	return strings.Join(formatted, ", ")
+}
@@ -0,0 +1,32 @@
+package mail
+
+import (
+	"net/textproto"
+	"reflect"
+	"testing"
+)
+
+func TestParseAddressHeader(t *testing.T) {
+	header := textproto.MIMEHeader{
+		"To": []string{`"Watney, Mark" <mark@mars.com>, melissa@hermes.com`},
+		"Cc": []string{"=?UTF-8?Q?J=C3=B6rg?= <joerg@nasa.gov>", "venkat@nasa.gov"},
+		// Broken header of a mail, which is still parsed as well as possible
+		"Reply-To": []string{"<rick@hermes.com>, broken <"},
+	}
+	for target, expected := range map[string][]Address{
+		"To": {{Name: "Watney, Mark", Address: "mark@mars.com"},
+			{Address: "melissa@hermes.com"}},
+		"Cc":       {{Name: "Jörg", Address: "joerg@nasa.gov"}, {Address: "venkat@nasa.gov"}},
+		"Reply-To": {{Address: "rick@hermes.com"}},
+		"Bcc":      nil,
+	} {
+		if addresses := parseAddressHeader(header, target); !reflect.DeepEqual(addresses,
+			expected) {
+			t.Errorf("Expected %s addresses %v, but got %v", target, expected, addresses)
+		}
+	}
+	if formatted := formatAddresses(parseAddressHeader(header, "To")); formatted !=
+		`"Watney, Mark" <mark@mars.com>, <melissa@hermes.com>` {
+		t.Errorf("Unexpected formatted addresses: %s", formatted)
+	}
+}
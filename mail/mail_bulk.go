@@ -0,0 +1,146 @@
+package mail
+
+import (
+	"errors"
+	"fmt"
+	"github.com/mxk/go-imap/imap"
+)
+
+// The outcome of a bulk operation (e.g., MoveMails) for a single mail
+type BulkResult struct {
+	// The UID of the mail the operation has been applied to
+	UID uint32
+	// The UID of the mail in the target folder after a move (0 = not reported by the server)
+	NewUID uint32
+	// Why the operation failed for this mail (empty, if it succeeded)
+	Error string
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////
+///									Public Bulk Methods											 ///
+////////////////////////////////////////////////////////////////////////////////////////////////////
+
+/**
+ * Moves all given mails from 'origFolder' into 'targetFolder' with a single command (see
+ * MoveMail).
+ * @return The result of each mail in the order of the given UIDs (mails, which don't exist in
+ *		   'origFolder', are reported as failed)
+ */
+func (mc *MailCon) MoveMails(uids []uint32, origFolder, targetFolder string) ([]BulkResult,
+	error) {
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+	return mc.bulk_internal(origFolder, uids, func(set *imap.SeqSet) (map[uint32]uint32, error) {
+		return mc.moveMails_internal(set, origFolder, targetFolder)
+	})
+}
+
+/**
+ * Moves all given mails from 'origFolder' into the Trash folder (see TrashMail).
+ * @return The result of each mail in the order of the given UIDs
+ */
+func (mc *MailCon) TrashMails(uids []uint32, origFolder string) ([]BulkResult, error) {
+	return mc.MoveMails(uids, origFolder, mc.SpecialFolder(SPECIAL_USE_TRASH))
+}
+
+/**
+ * Sets or removes the given flags of all given mails with a single command (see UpdateMailFlags).
+ * @param add True  - Sets the flag(s) as activated
+ *			  False - Removes the flag(s) (sets them as deactivated)
+ * @return The result of each mail in the order of the given UIDs
+ */
+func (mc *MailCon) UpdateMailsFlags(folder string, uids []uint32, f *Flags,
+	add bool) ([]BulkResult, error) {
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+	var item string = "-FLAGS"
+	if add {
+		item = "+FLAGS"
+	}
+	return mc.bulk_internal(folder, uids, func(set *imap.SeqSet) (map[uint32]uint32, error) {
+		if err := mc.checkKeywords_internal(f); err != nil {
+			return nil, err
+		}
+		_, err := mc.waitFor(mc.client.UIDStore(set, item, SerializeFlags(f)))
+		return nil, err
+	})
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////
+///									Private Bulk Methods										 ///
+////////////////////////////////////////////////////////////////////////////////////////////////////
+
+/**
+ * Applies the given operation to all given mails of the folder, which actually exist, and collects
+ * the result of each mail.
+ * ATTENTION: DOES NOT LOCK THE IMAP CONNECTION! => Has to be wrapped into a mutex lock method
+ * @param operation Performs the operation for the given UIDs and returns the new UIDs of the mails
+ *					(nil, if the operation doesn't create new mails)
+ */
+func (mc *MailCon) bulk_internal(folder string, uids []uint32,
+	operation func(set *imap.SeqSet) (map[uint32]uint32, error)) ([]BulkResult, error) {
+	if 0 == len(uids) {
+		return []BulkResult{}, errors.New("No mails given")
+	}
+	// 1) Only apply the operation to mails, which exist in the folder
+	existing, err := mc.existingUIDs_internal(folder, uids)
+	if err != nil {
+		return []BulkResult{}, err
+	}
+	var (
+		results []BulkResult = make([]BulkResult, len(uids))
+		set     *imap.SeqSet = new(imap.SeqSet)
+	)
+	for i, uid := range uids {
+		results[i].UID = uid
+		if existing[uid] {
+			set.AddNum(uid)
+		} else {
+			results[i].Error = fmt.Sprintf("No mail found for the given ID: %d", uid)
+		}
+	}
+	if set.Empty() {
+		return results, nil
+	}
+	// 2) The operation either succeeds or fails for all mails
+	newUIDs, err := operation(set)
+	for i := range results {
+		if !existing[results[i].UID] {
+			continue
+		}
+		if err != nil {
+			results[i].Error = err.Error()
+		} else {
+			results[i].NewUID = newUIDs[results[i].UID]
+		}
+	}
+	return results, err
+}
+
+/**
+ * Selects the given folder and checks which of the given mails exist in it.
+ * ATTENTION: DOES NOT LOCK THE IMAP CONNECTION! => Has to be wrapped into a mutex lock method
+ * @return UID -> true, for all existing mails
+ */
+func (mc *MailCon) existingUIDs_internal(folder string, uids []uint32) (map[uint32]bool, error) {
+	if err := mc.selectFolder(folder, false); err != nil {
+		return nil, err
+	}
+	var (
+		existing map[uint32]bool = map[uint32]bool{}
+		set      *imap.SeqSet    = new(imap.SeqSet)
+		cmd      *imap.Command
+		err      error
+	)
+	set.AddNum(uids...)
+	if cmd, err = mc.waitFor(mc.client.UIDSearch("UID", set)); err != nil {
+		return nil, err
+	}
+	for _, resp := range cmd.Data {
+		for _, uid := range resp.SearchResults() {
+			existing[uid] = true
+		}
+	}
+	mc.clearData()
+	return existing, nil
+}
@@ -0,0 +1,83 @@
+package mail
+
+import (
+	"fmt"
+	"golang.org/x/text/encoding/htmlindex"
+	"io"
+	"io/ioutil"
+	"mime"
+	"strings"
+	"unicode/utf8"
+)
+
+// Charsets with a special treatment in toUTF8 (all other charsets are looked up in htmlindex)
+const (
+	CHARSET_UTF8  string = "utf-8"
+	CHARSET_ASCII string = "us-ascii"
+)
+
+/**
+ * Converts the given text from the given charset into UTF-8. The charset can be given by any name
+ * of the WHATWG Encoding Standard (e.g., "ISO-8859-1" is decoded as its superset Windows-1252,
+ * since most mails labeled ISO-8859-1 use characters of Windows-1252, like the € sign). Invalid or
+ * undefined characters are replaced by U+FFFD.
+ * @return The text as is (with invalid UTF-8 sequences replaced) and an error, if the charset isn't
+ *		   supported
+ */
+func toUTF8(charset string, text []byte) (string, error) {
+	var name string = normalizeCharset(charset)
+	switch name {
+	case CHARSET_UTF8, "":
+		return validUTF8(text), nil
+	case CHARSET_ASCII, "ascii":
+		// 8-bit characters in mails labeled as ASCII are mostly UTF-8 or Windows-1252
+		if utf8.Valid(text) {
+			return string(text), nil
+		}
+	}
+	enc, err := htmlindex.Get(name)
+	if err != nil {
+		return validUTF8(text), fmt.Errorf("Unsupported charset '%s'", charset)
+	}
+	decoded, err := enc.NewDecoder().Bytes(text)
+	if err != nil {
+		return validUTF8(text), err
+	}
+	return string(decoded), nil
+}
+
+/**
+ * @return A decoder for encoded words of headers (RFC 2047), which supports all charsets of toUTF8.
+ *		   Words with an unsupported charset are kept as is, so the rest of the header can still be
+ *		   decoded.
+ */
+func newWordDecoder() *mime.WordDecoder {
+	return &mime.WordDecoder{
+		CharsetReader: func(charset string, input io.Reader) (io.Reader, error) {
+			text, err := ioutil.ReadAll(input)
+			if err != nil {
+				return nil, err
+			}
+			decoded, _ := toUTF8(charset, text)
+			return strings.NewReader(decoded), nil
+		},
+	}
+}
+
+/**
+ * @return The lower case name of the given charset without quotes, e.g., "'UTF-8'" -> "utf-8"
+ */
+func normalizeCharset(charset string) string {
+	return strings.ToLower(strings.Trim(strings.TrimSpace(charset), `"'`))
+}
+
+/**
+ * @return The given text with all invalid UTF-8 sequences replaced by U+FFFD
+ */
+func validUTF8(text []byte) string {
+	if utf8.Valid(text) {
+		return string(text)
+	}
+	// The conversion into runes replaces all invalid sequences
+	return string([]rune(string(text)))
+}
@@ -0,0 +1,61 @@
+package mail
+
+import (
+	"testing"
+)
+
+func TestToUTF8(t *testing.T) {
+	for _, test := range []struct {
+		charset  string
+		text     string
+		expected string
+	}{
+		{"UTF-8", "Grüße", "Grüße"},
+		{"utf-8", "Gr\xfc\xdfe", "Gr��e"},
+		{"ISO-8859-1", "Gr\xfc\xdfe \x96 \x84\x80\x93", "Grüße – „€“"},
+		{"us-ascii", "Gr\xfc\xdfe", "Grüße"},
+		{"iso8859-2", "Za\xbf\xf3\xb3\xe6", "Zażółć"},
+		{"KOI8-R", "\xf0\xd2\xc9\xd7\xc5\xd4", "Привет"},
+		{"ISO-2022-JP", "\x1b$BF|K\\8l$N%a!<%k!\"\x1b(BOK\r\n", "日本語のメール、OK\r\n"},
+		{"Shift_JIS", "\x93\xfa\x96{\x8c\xea\x82\xcc\x83\x81\x81[\x83\x8b\x81A\xb6\xc0\xb6\xc5",
+			"日本語のメール、ｶﾀｶﾅ"},
+		{"EUC-JP", "\xc6\xfc\xcb\xdc\xb8\xec\xa4\xce\xa5\xe1\xa1\xbc\xa5\xeb\xa1\xa2\x8e\xb6" +
+			"\x8e\xc0\x8e\xb6\x8e\xc5", "日本語のメール、ｶﾀｶﾅ"},
+		{"GBK", "\xc4\xe3\xba\xc3", "你好"},
+	} {
+		if text, err := toUTF8(test.charset, []byte(test.text)); err != nil ||
+			text != test.expected {
+			t.Errorf("Expected '%s' for charset %s, but got '%s' (%v)", test.expected,
+				test.charset, text, err)
+		}
+	}
+	if text, err := toUTF8("x-unknown", []byte("Gr\xfc\xdfe")); nil == err ||
+		text != "Gr��e" {
+		t.Errorf("Expected an error for an unknown charset, but got '%s' (%v)", text, err)
+	}
+}
+
+func TestDecodeContent(t *testing.T) {
+	content := Content{
+		"text/plain": ContentPart{Charset: "ISO-8859-1", Encoding: "quoted-printable",
+			Body: "Gr=FC=DFe"},
+		"text/html": ContentPart{Charset: "koi8-r", Encoding: "base64", Body: "8NLJ18XU\r\n"},
+	}
+	decodeContent(content)
+	if part := content["text/plain"]; part.Body != "Grüße" || part.Charset != "UTF-8" {
+		t.Errorf("Quoted-printable ISO-8859-1 part hasn't been decoded: %v", part)
+	}
+	if part := content["text/html"]; part.Body != "Привет" {
+		t.Errorf("Base64 KOI8-R part hasn't been decoded: %v", part)
+	}
+	if subject, err := newWordDecoder().DecodeHeader("=?koi8-r?B?8NLJ18XU?= =?UTF-8?Q?!?="); err !=
+		nil || subject != "Привет!" {
+		t.Errorf("Encoded words haven't been decoded: '%s' (%v)", subject, err)
+	}
+	// Words with an unknown charset must not prevent decoding the rest of the header
+	if subject, err := newWordDecoder().DecodeHeader(
+		"=?x-unknown?Q?Mars?= =?koi8-r?B?8NLJ18XU?="); err != nil || subject != "MarsПривет" {
+		t.Errorf("Encoded words with unknown charset haven't been decoded: '%s' (%v)", subject,
+			err)
+	}
+}
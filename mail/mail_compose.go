@@ -0,0 +1,333 @@
+package mail
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// Maximum line length of base64 encoded bodies (RFC 2045)
+const BASE64_LINE_LENGTH int = 76
+
+// A file, that is attached to an outgoing mail
+type Attachment struct {
+	// The file name shown to the receiver
+	Filename string
+	// The media-type of the file (default: application/octet-stream)
+	ContentType string
+	// The raw content of the file
+	Data []byte `json:"-"`
+}
+
+// A mail to be sent, which is converted into a MIME message with BuildMessage
+type OutgoingMail struct {
+	// The sender of the mail (From:)
+	From string
+	// The receivers of the mail (To:), each entry can contain a comma separated list of addresses
+	To []string
+	// The carbon copy receivers of the mail (Cc:)
+	Cc []string
+	// The blind carbon copy receivers of the mail, which receive the mail, but aren't listed in
+	// its header (neither in the delivered nor in the stored message)
+	Bcc []string
+	// The addresses replies to this mail should be sent to (Reply-To:)
+	ReplyTo []string
+	// The subject of the mail (Subject:)
+	Subject string
+	// The plain text body of the mail
+	Text string
+	// The HTML body of the mail (optional, sent as alternative to the plain text body)
+	HTML string
+	// All files attached to the mail
+	Attachments []Attachment
+	// The date of the mail (default: the time the message is built)
+	Date time.Time
+	// The unique ID of the mail (default: generated when the message is built)
+	MessageID string
+	// The ID of the mail this mail is a reply to (In-Reply-To:)
+	InReplyTo string
+	// The IDs of all preceding mails of the discussion, oldest first (References:)
+	References []string
+	// The original mail, if this mail is a reply or forward (see ComposeReply)
+	Origin *MailReference
+	// The UID of the draft this mail has been composed from, which is removed once the mail has
+	// been sent (0 = no draft)
+	DraftUID uint32
+}
+
+// Writes a MIME part, whose header has been written already
+type partWriter func(w io.Writer) error
+
+////////////////////////////////////////////////////////////////////////////////////////////////////
+///									Public Compose Methods										 ///
+////////////////////////////////////////////////////////////////////////////////////////////////////
+
+/**
+ * Builds the raw MIME message of the given mail, which can be sent via SMTP and appended to a
+ * folder as is. The structure of the message depends on its content:
+ *	- Text only: text/plain
+ *	- Text and HTML: multipart/alternative (text/plain, text/html)
+ *	- With attachments: multipart/mixed (text/plain or multipart/alternative, attachments...)
+ * Text parts are quoted-printable, attachments base64 encoded.
+ */
+func BuildMessage(m *OutgoingMail) ([]byte, error) {
+	return buildMessage(m, false)
+}
+
+/**
+ * @return The bare mail addresses of all receivers (To, Cc and Bcc) as needed for SMTP
+ */
+func (m *OutgoingMail) Recipients() ([]string, error) {
+	var recipients []string = []string{}
+	addresses, err := ParseAddresses(append(append(append([]string{}, m.To...), m.Cc...),
+		m.Bcc...))
+	if err != nil {
+		return recipients, err
+	}
+	for _, addr := range addresses {
+		recipients = append(recipients, addr.Address)
+	}
+	if 0 == len(recipients) {
+		return recipients, errors.New("The mail doesn't have any receivers")
+	}
+	return recipients, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////
+///									Private Compose Methods										 ///
+////////////////////////////////////////////////////////////////////////////////////////////////////
+
+/**
+ * @param withBcc	True - The Bcc receivers are written into the header (only used for drafts)
+ *					False - The Bcc receivers are omitted (see BuildMessage)
+ */
+func buildMessage(m *OutgoingMail, withBcc bool) ([]byte, error) {
+	if m.Date.IsZero() {
+		m.Date = time.Now()
+	}
+	if 0 == len(m.MessageID) {
+		m.MessageID = generateMessageID(m.From)
+	}
+	var (
+		buf    *bytes.Buffer = new(bytes.Buffer)
+		header []string
+	)
+	from, err := m.sender()
+	if err != nil {
+		return nil, err
+	}
+	header = []string{
+		fmt.Sprintf("Date: %s", m.Date.Format(time.RFC1123Z)),
+		fmt.Sprintf("From: %s", from.String()),
+	}
+	// Bcc receivers are only written into the header of drafts
+	var (
+		fieldNames []string   = []string{"To", "Cc", "Reply-To"}
+		recipients [][]string = [][]string{m.To, m.Cc, m.ReplyTo}
+	)
+	if withBcc {
+		fieldNames, recipients = append(fieldNames, "Bcc"), append(recipients, m.Bcc)
+	}
+	for i, fieldName := range fieldNames {
+		addresses, err := ParseAddresses(recipients[i])
+		if err != nil {
+			return nil, err
+		}
+		if len(addresses) > 0 {
+			header = append(header, fmt.Sprintf("%s: %s", fieldName, formatAddresses(addresses)))
+		}
+	}
+	header = append(header,
+		fmt.Sprintf("Subject: %s", mime.QEncoding.Encode("UTF-8", m.Subject)),
+		fmt.Sprintf("Message-ID: %s", m.MessageID))
+	if len(m.InReplyTo) > 0 {
+		header = append(header, fmt.Sprintf("In-Reply-To: %s", m.InReplyTo))
+	}
+	if len(m.References) > 0 {
+		header = append(header, fmt.Sprintf("References: %s",
+			strings.Join(m.References, "\r\n\t")))
+	}
+	header = append(header, "MIME-Version: 1.0")
+	buf.WriteString(strings.Join(header, "\r\n") + "\r\n")
+	// 1) The body is either the plain text or the alternative of plain text and HTML
+	var (
+		bodyHeader textproto.MIMEHeader
+		body       partWriter
+	)
+	if 0 == len(m.HTML) {
+		bodyHeader, body = textPart("text/plain", m.Text)
+	} else {
+		bodyHeader, body = alternativePart(m.Text, m.HTML)
+	}
+	if 0 == len(m.Attachments) {
+		writeHeader(buf, bodyHeader)
+		if err := body(buf); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	// 2) Attachments are added as further parts of a multipart/mixed message
+	mixed := multipart.NewWriter(buf)
+	writeHeader(buf, textproto.MIMEHeader{"Content-Type": {
+		mime.FormatMediaType("multipart/mixed", map[string]string{"boundary": mixed.Boundary()})}})
+	if err := writePart(mixed, bodyHeader, body); err != nil {
+		return nil, err
+	}
+	for _, attachment := range m.Attachments {
+		attHeader, attBody := attachmentPart(attachment)
+		if err := writePart(mixed, attHeader, attBody); err != nil {
+			return nil, err
+		}
+	}
+	if err := mixed.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+/**
+ * @return The parsed sender of the mail (exactly one address is allowed)
+ */
+func (m *OutgoingMail) sender() (Address, error) {
+	addresses, err := ParseAddresses([]string{m.From})
+	if err != nil {
+		return Address{}, err
+	}
+	if 1 != len(addresses) {
+		return Address{}, fmt.Errorf("Expected exactly one sender, but got '%s'", m.From)
+	}
+	return addresses[0], nil
+}
+
+/**
+ * @return The header and writer of a quoted-printable encoded text part
+ */
+func textPart(contentType, text string) (textproto.MIMEHeader, partWriter) {
+	return textproto.MIMEHeader{
+		"Content-Type": {mime.FormatMediaType(contentType,
+			map[string]string{"charset": "UTF-8"})},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	}, func(w io.Writer) error {
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write([]byte(text)); err != nil {
+			return err
+		}
+		return qp.Close()
+	}
+}
+
+/**
+ * @return The header and writer of a multipart/alternative part with a plain text and HTML part
+ */
+func alternativePart(text, html string) (textproto.MIMEHeader, partWriter) {
+	var boundary string = randomBoundary()
+	return textproto.MIMEHeader{
+		"Content-Type": {mime.FormatMediaType("multipart/alternative",
+			map[string]string{"boundary": boundary})},
+	}, func(w io.Writer) error {
+		alternative := multipart.NewWriter(w)
+		if err := alternative.SetBoundary(boundary); err != nil {
+			return err
+		}
+		textHeader, textBody := textPart("text/plain", text)
+		if err := writePart(alternative, textHeader, textBody); err != nil {
+			return err
+		}
+		htmlHeader, htmlBody := textPart("text/html", html)
+		if err := writePart(alternative, htmlHeader, htmlBody); err != nil {
+			return err
+		}
+		return alternative.Close()
+	}
+}
+
+/**
+ * @return The header and writer of a base64 encoded attachment part
+ */
+func attachmentPart(attachment Attachment) (textproto.MIMEHeader, partWriter) {
+	var contentType string = attachment.ContentType
+	if 0 == len(contentType) {
+		contentType = "application/octet-stream"
+	}
+	// Non-ASCII file names are encoded as encoded words, which is understood by most mail clients
+	var filename string = mime.QEncoding.Encode("UTF-8", attachment.Filename)
+	return textproto.MIMEHeader{
+		"Content-Type": {mime.FormatMediaType(contentType,
+			map[string]string{"name": filename})},
+		"Content-Disposition": {mime.FormatMediaType(DISPOSITION_ATTACHMENT,
+			map[string]string{"filename": filename})},
+		"Content-Transfer-Encoding": {"base64"},
+	}, func(w io.Writer) error {
+		return writeBase64(w, attachment.Data)
+	}
+}
+
+/**
+ * Writes the given data base64 encoded with lines of at most BASE64_LINE_LENGTH characters.
+ */
+func writeBase64(w io.Writer, data []byte) error {
+	var encoded string = base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 0 {
+		var line string = encoded
+		if len(line) > BASE64_LINE_LENGTH {
+			line = encoded[:BASE64_LINE_LENGTH]
+		}
+		if _, err := io.WriteString(w, line+"\r\n"); err != nil {
+			return err
+		}
+		encoded = encoded[len(line):]
+	}
+	return nil
+}
+
+func writePart(mw *multipart.Writer, header textproto.MIMEHeader, body partWriter) error {
+	w, err := mw.CreatePart(header)
+	if err != nil {
+		return err
+	}
+	return body(w)
+}
+
+/**
+ * Writes the given header followed by the empty line, which separates it from the body.
+ */
+func writeHeader(w io.Writer, header textproto.MIMEHeader) {
+	for _, key := range []string{"Content-Type", "Content-Transfer-Encoding"} {
+		for _, value := range header[key] {
+			fmt.Fprintf(w, "%s: %s\r\n", key, value)
+		}
+	}
+	io.WriteString(w, "\r\n")
+}
+
+/**
+ * Generates a unique Message-ID for the domain of the given sender, e.g.:
+ *	"mark@mars.com" -> "<1443001234.5f3a9c0e1b2d4e6f@mars.com>"
+ */
+func generateMessageID(from string) string {
+	var (
+		domain string = "watney.localhost"
+		random []byte = make([]byte, 8)
+	)
+	if at := strings.LastIndex(from, "@"); at >= 0 {
+		domain = strings.Trim(from[at+1:], "<> ")
+	}
+	rand.Read(random)
+	return fmt.Sprintf("<%d.%s@%s>", time.Now().Unix(), hex.EncodeToString(random), domain)
+}
+
+func randomBoundary() string {
+	var random []byte = make([]byte, 24)
+	rand.Read(random)
+	return hex.EncodeToString(random)
+}
@@ -0,0 +1,78 @@
+package mail
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBuildMessage(t *testing.T) {
+	msg, err := BuildMessage(&OutgoingMail{
+		From:    "mark@mars.com",
+		To:      []string{"Melissa Lewis <melissa@hermes.com>, rick@hermes.com"},
+		Cc:      []string{"venkat@nasa.gov"},
+		Bcc:     []string{"annie@nasa.gov"},
+		Subject: "Kartoffeln für alle",
+		Text:    "Potatoes!",
+		HTML:    "<b>Potatoes!</b>",
+		Attachments: []Attachment{
+			{Filename: "crops.csv", ContentType: "text/csv", Data: []byte("potatoes;42")},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.SplitN(string(msg), "\r\n\r\n", 2)
+	header, err := parseHeaderStr(parts[0] + "\r\n\r\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(parts[0], "annie@nasa.gov") {
+		t.Errorf("Bcc receivers must not be contained in the message header")
+	}
+	if len(header.To) != 2 || header.To[0].Name != "Melissa Lewis" || len(header.Cc) != 1 {
+		t.Errorf("Unexpected receivers: To %v, Cc %v", header.To, header.Cc)
+	}
+	if header.Subject != "Kartoffeln für alle" || !strings.HasSuffix(header.MessageID,
+		"@mars.com>") || header.MimeHeader.ContentType != "multipart/mixed" {
+		t.Fatalf("Unexpected message header: %v", header)
+	}
+	tree, err := parseMultipartParts(parts[1], header.MimeHeader.MultipartBoundary, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tree) != 2 || tree[0].ContentType != "multipart/alternative" ||
+		len(tree[0].Children) != 2 || tree[0].Children[1].ContentType != "text/html" {
+		t.Fatalf("Unexpected message structure: %v", tree)
+	}
+	attachment := tree[1]
+	body, err := decodeBody(attachment.Encoding, []byte(attachment.body))
+	if err != nil || attachment.Filename != "crops.csv" || !attachment.IsAttachment() ||
+		string(body) != "potatoes;42" {
+		t.Errorf("Unexpected attachment %v with body '%s' (%v)", attachment, body, err)
+	}
+}
+
+func TestWriteBase64(t *testing.T) {
+	var buf bytes.Buffer
+	writeBase64(&buf, make([]byte, 100))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\r\n")
+	if len(lines) != 2 || len(lines[0]) != BASE64_LINE_LENGTH {
+		t.Errorf("Expected 2 base64 lines of at most %d characters, but got %v",
+			BASE64_LINE_LENGTH, lines)
+	}
+}
+
+func TestRecipients(t *testing.T) {
+	m := &OutgoingMail{To: []string{"Mark <mark@mars.com>, melissa@hermes.com"},
+		Bcc: []string{"annie@nasa.gov"}}
+	recipients, err := m.Recipients()
+	if err != nil || !reflect.DeepEqual(recipients,
+		[]string{"mark@mars.com", "melissa@hermes.com", "annie@nasa.gov"}) {
+		t.Errorf("Unexpected recipients %v (%v)", recipients, err)
+	}
+	if _, err := (&OutgoingMail{To: []string{"not an address"}}).Recipients(); err == nil {
+		t.Errorf("Expected an error for an invalid receiver")
+	}
+}
@@ -0,0 +1,131 @@
+package mail
+
+import (
+	"fmt"
+	"github.com/mxk/go-imap/imap"
+	"sort"
+	"time"
+)
+
+////////////////////////////////////////////////////////////////////////////////////////////////////
+///									Public Draft Methods										 ///
+////////////////////////////////////////////////////////////////////////////////////////////////////
+
+/**
+ * Saves the given mail as draft in the Drafts folder (the \Drafts special-use folder) with the
+ * \Draft flag set. In contrast to sent mails, drafts keep their Bcc receivers.
+ * @param replaceUID The UID of the previous version of the draft, which is removed once the new
+ *					 version has been saved (0 = new draft)
+ * @return The UID of the saved draft
+ */
+func (mc *MailCon) SaveDraft(m *OutgoingMail, replaceUID uint32) (uint32, error) {
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+	// Each version of the draft is a new message => it needs a new date and ID
+	m.Date, m.MessageID = time.Time{}, ""
+	msg, err := buildMessage(m, true)
+	if err != nil {
+		return 0, err
+	}
+	uid, err := mc.appendMessage_internal(mc.specialFolder(SPECIAL_USE_DRAFTS),
+		&Flags{Seen: true, Draft: true}, m.Date, msg)
+	if err != nil {
+		return 0, err
+	}
+	if replaceUID > 0 {
+		if err = mc.removeDraft_internal(replaceUID); err != nil {
+			return uid, err
+		}
+	}
+	return uid, nil
+}
+
+/**
+ * Removes the draft with the given UID from the Drafts folder.
+ */
+func (mc *MailCon) DeleteDraft(uid uint32) error {
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+	return mc.removeDraft_internal(uid)
+}
+
+/**
+ * Loads all drafts of the Drafts folder without their content (newest first).
+ */
+func (mc *MailCon) LoadDrafts() ([]Mail, error) {
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+	set, _ := imap.NewSeqSet("1:*")
+	mails, err := mc.loadMails(set, mc.specialFolder(SPECIAL_USE_DRAFTS), false, mc.client.Fetch)
+	if err != nil {
+		return []Mail{}, err
+	}
+	var drafts []Mail = []Mail{}
+	for _, curMail := range mails {
+		// Ignore previous versions of drafts, which haven't been expunged yet
+		if !curMail.Flags.Deleted {
+			drafts = append(drafts, curMail)
+		}
+	}
+	sort.Sort(MailSlice(drafts))
+	return drafts, nil
+}
+
+/**
+ * Loads the draft with the given UID to resume composing it.
+ * @return The draft including its receivers, body and attachments
+ */
+func (mc *MailCon) LoadDraft(uid uint32) (*OutgoingMail, error) {
+	draft, err := mc.LoadMailFromFolderWithUID(mc.SpecialFolder(SPECIAL_USE_DRAFTS), uid)
+	if err != nil {
+		return nil, err
+	}
+	return outgoingFromDraft(draft)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////
+///									Private Draft Methods										 ///
+////////////////////////////////////////////////////////////////////////////////////////////////////
+
+/**
+ * Permanently removes the draft with the given UID from the Drafts folder.
+ * ATTENTION: DOES NOT LOCK THE IMAP CONNECTION! => Has to be wrapped into a mutex lock method
+ */
+func (mc *MailCon) removeDraft_internal(uid uint32) error {
+	if err := mc.selectFolder(mc.specialFolder(SPECIAL_USE_DRAFTS), false); err != nil {
+		return err
+	}
+	set, _ := imap.NewSeqSet(fmt.Sprintf("%d", uid))
+	_, err := mc.deleteMails_internal(set)
+	return err
+}
+
+/**
+ * Converts the given draft back into an outgoing mail.
+ */
+func outgoingFromDraft(draft Mail) (*OutgoingMail, error) {
+	var outgoing *OutgoingMail = &OutgoingMail{
+		From:       formatAddresses(draft.Header.From),
+		Subject:    draft.Header.Subject,
+		InReplyTo:  draft.Header.InReplyTo,
+		References: draft.Header.References,
+	}
+	for _, recipients := range []struct {
+		target    *[]string
+		addresses []Address
+	}{{&outgoing.To, draft.Header.To}, {&outgoing.Cc, draft.Header.Cc},
+		{&outgoing.Bcc, draft.Header.Bcc}, {&outgoing.ReplyTo, draft.Header.ReplyTo}} {
+		for _, addr := range recipients.addresses {
+			*recipients.target = append(*recipients.target, addr.String())
+		}
+	}
+	if part, ok := draft.Content["text/plain"]; ok {
+		outgoing.Text = part.Body
+	}
+	if part, ok := draft.Content["text/html"]; ok {
+		outgoing.HTML = part.Body
+	}
+	var err error
+	outgoing.Attachments, err = draft.Parts.decodeAttachments()
+	return outgoing, err
+}
@@ -0,0 +1,67 @@
+package mail
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestDraftKeepsBcc(t *testing.T) {
+	var m *OutgoingMail = &OutgoingMail{
+		From:    "me@hermes.com",
+		To:      []string{"mark@mars.com"},
+		Bcc:     []string{"venkat@nasa.gov"},
+		Subject: "Potatoes",
+		Text:    "Grow them!",
+	}
+	draft, err := buildMessage(m, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(draft, []byte("\r\nBcc: <venkat@nasa.gov>\r\n")) {
+		t.Errorf("Draft doesn't contain the Bcc receivers:\n%s", draft)
+	}
+	sent, err := BuildMessage(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(sent, []byte("Bcc:")) {
+		t.Errorf("Sent message contains the Bcc receivers:\n%s", sent)
+	}
+}
+
+func TestOutgoingFromDraft(t *testing.T) {
+	var draft Mail = Mail{
+		UID: 7,
+		Header: &Header{
+			Subject:   "Potatoes",
+			From:      []Address{{Name: "Me", Address: "me@hermes.com"}},
+			To:        []Address{{Address: "mark@mars.com"}},
+			Bcc:       []Address{{Address: "venkat@nasa.gov"}},
+			InReplyTo: "<2@mars>",
+		},
+		Content: Content{"text/plain": ContentPart{Body: "Grow them!"}},
+		Parts: &MailPart{Children: []*MailPart{
+			{Section: "1", ContentType: "text/plain", body: "Grow them!"},
+			{Section: "2", ContentType: "text/csv", Encoding: "base64",
+				Disposition: DISPOSITION_ATTACHMENT, Filename: "sols.csv", body: "MSwy\r\n"},
+		}},
+	}
+	outgoing, err := outgoingFromDraft(draft)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if outgoing.From != `"Me" <me@hermes.com>` || outgoing.Subject != "Potatoes" ||
+		outgoing.Text != "Grow them!" || outgoing.InReplyTo != "<2@mars>" {
+		t.Errorf("Unexpected draft: %v", outgoing)
+	}
+	if !reflect.DeepEqual(outgoing.To, []string{"<mark@mars.com>"}) ||
+		!reflect.DeepEqual(outgoing.Bcc, []string{"<venkat@nasa.gov>"}) || len(outgoing.Cc) != 0 {
+		t.Errorf("Unexpected receivers: To %v, Cc %v, Bcc %v", outgoing.To, outgoing.Cc,
+			outgoing.Bcc)
+	}
+	if 1 != len(outgoing.Attachments) || outgoing.Attachments[0].Filename != "sols.csv" ||
+		string(outgoing.Attachments[0].Data) != "1,2" {
+		t.Errorf("Unexpected attachments: %v", outgoing.Attachments)
+	}
+}
@@ -0,0 +1,309 @@
+package mail
+
+import (
+	"fmt"
+	"github.com/mxk/go-imap/imap"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Types of the events published to all registered event listeners of a MailCon
+const (
+	NEW_MAIL_EVENT string = "newMail" // New mails have arrived in the folder
+	EXPUNGE_EVENT  string = "expunge" // A mail has been removed from the folder
+	FLAGS_EVENT    string = "flags"   // The flags of a mail have been changed
+)
+
+const (
+	// Time after which an IDLE command is re-issued (RFC 2177 recommends at most 29 minutes)
+	IDLE_TIMEOUT time.Duration = 25 * time.Minute
+	// Time to wait for server updates, before checking whether the IDLE command has to be stopped
+	IDLE_POLL_INTERVAL time.Duration = 250 * time.Millisecond
+	// Number of events that are buffered for each listener, before events are dropped
+	EVENT_BUFFER_SIZE int = 64
+)
+
+// An update of a folder, that has been pushed by the IMAP server (EXISTS, EXPUNGE, FETCH)
+type MailEvent struct {
+	// The type of the event: NEW_MAIL_EVENT | EXPUNGE_EVENT | FLAGS_EVENT
+	Type string
+	// The folder in which the event occurred ("/" = root)
+	Folder string
+	// The sequence numbers of the affected mails
+	SeqNbrs []uint32
+	// The UID of the affected mail (only for FLAGS_EVENT, if provided by the server)
+	UID uint32
+	// The number of mails in the folder after the event occurred
+	Exists uint32
+	// The new flags of the affected mail (only for FLAGS_EVENT)
+	Flags *Flags
+	// The new mails including their content (only for NEW_MAIL_EVENT)
+	// ATTENTION: The mails are shared by all listeners => don't modify them
+	Mails []Mail
+}
+
+// A mutex that tracks, whether the IMAP connection is requested by another goroutine. This is used
+// to stop a running IDLE command as soon as the connection is needed.
+type imapMutex struct {
+	sync.Mutex
+	// Number of goroutines currently waiting to lock the mutex
+	waiting int32
+}
+
+func (m *imapMutex) Lock() {
+	atomic.AddInt32(&m.waiting, 1)
+	m.Mutex.Lock()
+	atomic.AddInt32(&m.waiting, -1)
+}
+
+func (m *imapMutex) isRequested() bool {
+	return atomic.LoadInt32(&m.waiting) > 0
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////
+///									Public Event Methods										 ///
+////////////////////////////////////////////////////////////////////////////////////////////////////
+
+/**
+ * Registers a new listener for all folder updates pushed by the IMAP server. The returned channel
+ * is closed, when the IMAP connection is closed.
+ * ATTENTION: The listener has to be removed with RemoveEventListener, once it isn't used anymore.
+ */
+func (mc *MailCon) AddEventListener() chan MailEvent {
+	mc.listenerMutex.Lock()
+	defer mc.listenerMutex.Unlock()
+	var listener chan MailEvent = make(chan MailEvent, EVENT_BUFFER_SIZE)
+	if nil == mc.listeners {
+		mc.listeners = make(map[chan MailEvent]bool)
+	}
+	mc.listeners[listener] = true
+	return listener
+}
+
+func (mc *MailCon) RemoveEventListener(listener chan MailEvent) {
+	mc.listenerMutex.Lock()
+	defer mc.listenerMutex.Unlock()
+	if _, ok := mc.listeners[listener]; ok {
+		delete(mc.listeners, listener)
+		close(listener)
+	}
+}
+
+/**
+ * Returns all events, that have been published since the last call of this method (for clients,
+ * which poll for updates instead of listening for them). The first call only starts collecting
+ * the events and returns none.
+ */
+func (mc *MailCon) PollEvents() []MailEvent {
+	mc.pollMutex.Lock()
+	defer mc.pollMutex.Unlock()
+	var events []MailEvent = []MailEvent{}
+	if nil == mc.pollListener {
+		mc.pollListener = mc.AddEventListener()
+		return events
+	}
+	for {
+		select {
+		case event, ok := <-mc.pollListener:
+			if !ok {
+				// The IMAP connection has been closed
+				mc.pollListener = nil
+				return events
+			}
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////
+///									Private Event Methods										 ///
+////////////////////////////////////////////////////////////////////////////////////////////////////
+
+/**
+ * Waits for updates of the INBOX with the IDLE command, until either the IMAP connection is
+ * requested by another goroutine or the IDLE_TIMEOUT is reached.
+ * ATTENTION: DOES NOT LOCK THE IMAP CONNECTION! => Has to be wrapped into a mutex lock method
+ */
+func (mc *MailCon) idle() {
+	// 1) IDLE only reports updates of the selected folder => watch the INBOX
+	if mc.client.State() != imap.Selected || mc.selectedFolder != "/" {
+		if err := mc.selectFolder("/", true); err != nil {
+			mc.logMC(fmt.Sprintf("Couldn't select INBOX for IDLE: %s", err.Error()), imap.LogAll)
+			return
+		}
+	}
+	if _, err := mc.client.Idle(); err != nil {
+		mc.logMC(fmt.Sprintf("Couldn't start IDLE: %s", err.Error()), imap.LogAll)
+		return
+	}
+	// 2) Receive and publish updates, until the connection is needed elsewhere
+	var start time.Time = time.Now()
+	for !mc.mutex.isRequested() && time.Since(start) < IDLE_TIMEOUT {
+		if err := mc.client.Recv(IDLE_POLL_INTERVAL); err != nil && err != imap.ErrTimeout {
+			mc.logMC(fmt.Sprintf("Error while waiting for IDLE updates: %s", err.Error()),
+				imap.LogAll)
+			break
+		}
+		mc.dispatchUpdates()
+		if len(mc.newMailFolders) > 0 {
+			// New mails have to be loaded, which isn't possible during IDLE
+			break
+		}
+	}
+	// 3) Terminate the IDLE command to free the connection for other commands
+	if _, err := mc.waitFor(mc.client.IdleTerm()); err != nil {
+		mc.logMC(err.Error(), imap.LogAll)
+	}
+}
+
+/**
+ * Publishes an event for all server updates in the data queue, that haven't been published yet.
+ * New mails are only remembered, since they are published with their content (see
+ * publishNewMails).
+ * ATTENTION: DOES NOT LOCK THE IMAP CONNECTION! => Has to be wrapped into a mutex lock method
+ */
+func (mc *MailCon) dispatchUpdates() {
+	for ; mc.dispatched < len(mc.client.Data); mc.dispatched++ {
+		if event, ok := mc.parseUpdate(mc.client.Data[mc.dispatched]); !ok {
+			continue
+		} else if NEW_MAIL_EVENT == event.Type {
+			if nil == mc.newMailFolders {
+				mc.newMailFolders = make(map[string]bool)
+			}
+			mc.newMailFolders[event.Folder] = true
+		} else {
+			mc.publish(event)
+		}
+	}
+}
+
+/**
+ * Loads the new mails of all folders, for which the server announced new mails, and publishes
+ * them with one NEW_MAIL_EVENT per folder. The mails are loaded only once for all listeners, since
+ * loading them marks them as known. As long as nobody listens, the new mails stay unknown and are
+ * published, once a listener has been registered.
+ * ATTENTION: DOES NOT LOCK THE IMAP CONNECTION! => Has to be wrapped into a mutex lock method
+ */
+func (mc *MailCon) publishNewMails() {
+	if 0 == len(mc.newMailFolders) || !mc.hasEventListeners() {
+		return
+	}
+	var folders []string = make([]string, 0, len(mc.newMailFolders))
+	for folder := range mc.newMailFolders {
+		folders = append(folders, folder)
+	}
+	for _, folder := range folders {
+		// Mails arriving while loading announce the folder again
+		delete(mc.newMailFolders, folder)
+		mails, err := mc.loadNewMails_internal(folder)
+		if err != nil {
+			mc.logMC(fmt.Sprintf("Couldn't load new mails of folder '%s': %s", folder,
+				err.Error()), imap.LogAll)
+			mc.newMailFolders[folder] = true
+			continue
+		}
+		if len(mails) > 0 {
+			sort.Sort(MailSlice(mails))
+			mc.publish(MailEvent{Type: NEW_MAIL_EVENT, Folder: folder, Mails: mails,
+				Exists: mc.lastExists})
+		}
+	}
+}
+
+/**
+ * Publishes all pending updates and empties the data queue afterwards.
+ * ATTENTION: DOES NOT LOCK THE IMAP CONNECTION! => Has to be wrapped into a mutex lock method
+ */
+func (mc *MailCon) clearData() {
+	mc.dispatchUpdates()
+	mc.client.Data = nil
+	mc.dispatched = 0
+}
+
+/**
+ * Converts the given unilateral server response into an event for the selected folder:
+ *  - "* 25 EXISTS" -> NEW_MAIL_EVENT (if the folder contains more mails than before)
+ *  - "* 3 EXPUNGE" -> EXPUNGE_EVENT
+ *  - "* 3 FETCH (FLAGS (\Seen))" -> FLAGS_EVENT
+ * @return The event and true, if the response is one of the above, false otherwise
+ */
+func (mc *MailCon) parseUpdate(resp *imap.Response) (MailEvent, bool) {
+	if resp.Type != imap.Data || len(resp.Fields) < 2 {
+		return MailEvent{}, false
+	}
+	var (
+		n     uint32    = imap.AsNumber(resp.Fields[0])
+		event MailEvent = MailEvent{Folder: mc.selectedFolder}
+	)
+	switch strings.ToUpper(resp.Label) {
+	case "EXISTS":
+		if n <= mc.lastExists {
+			mc.lastExists = n
+			return MailEvent{}, false
+		}
+		event.Type = NEW_MAIL_EVENT
+		for seq := mc.lastExists + 1; seq <= n; seq++ {
+			event.SeqNbrs = append(event.SeqNbrs, seq)
+		}
+		mc.lastExists = n
+	case "EXPUNGE":
+		event.Type = EXPUNGE_EVENT
+		event.SeqNbrs = []uint32{n}
+		if mc.lastExists > 0 {
+			mc.lastExists--
+		}
+	case "FETCH":
+		info := resp.MessageInfo()
+		if nil == info || nil == info.Flags {
+			return MailEvent{}, false
+		}
+		event.Type = FLAGS_EVENT
+		event.SeqNbrs = []uint32{n}
+		event.UID = info.UID
+		event.Flags = readFlags(info)
+	default:
+		return MailEvent{}, false
+	}
+	event.Exists = mc.lastExists
+	return event, true
+}
+
+/**
+ * Sends the given event to all registered listeners. Listeners, which don't consume their events,
+ * miss the event.
+ */
+func (mc *MailCon) publish(event MailEvent) {
+	mc.listenerMutex.Lock()
+	defer mc.listenerMutex.Unlock()
+	for listener := range mc.listeners {
+		select {
+		case listener <- event:
+		default:
+			mc.logMC(fmt.Sprintf("Dropped %s event for folder '%s', because the listener is busy",
+				event.Type, event.Folder), imap.LogAll)
+		}
+	}
+}
+
+func (mc *MailCon) hasEventListeners() bool {
+	mc.listenerMutex.Lock()
+	defer mc.listenerMutex.Unlock()
+	return len(mc.listeners) > 0
+}
+
+/**
+ * Closes and removes all registered listeners (used, when the IMAP connection is closed).
+ */
+func (mc *MailCon) removeAllEventListeners() {
+	mc.listenerMutex.Lock()
+	defer mc.listenerMutex.Unlock()
+	for listener := range mc.listeners {
+		close(listener)
+	}
+	mc.listeners = nil
+}
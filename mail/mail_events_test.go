@@ -0,0 +1,107 @@
+package mail
+
+import (
+	"fmt"
+	"github.com/mxk/go-imap/imap"
+	"github.com/mxk/go-imap/mock"
+	"reflect"
+	"testing"
+)
+
+func TestParseUpdate(t *testing.T) {
+	var mc *MailCon = &MailCon{selectedFolder: "/", lastExists: 23}
+	// 1) Two new mails have arrived
+	event, ok := mc.parseUpdate(&imap.Response{Type: imap.Data, Label: "EXISTS",
+		Fields: []imap.Field{uint32(25), "EXISTS"}})
+	if !ok || event.Type != NEW_MAIL_EVENT || event.Folder != "/" || event.Exists != 25 ||
+		!reflect.DeepEqual(event.SeqNbrs, []uint32{24, 25}) {
+		t.Fatalf("Expected new mail event for sequence numbers 24 and 25, but got: %v", event)
+	}
+	// 2) An EXISTS without new mails is no event
+	if event, ok = mc.parseUpdate(&imap.Response{Type: imap.Data, Label: "EXISTS",
+		Fields: []imap.Field{uint32(25), "EXISTS"}}); ok {
+		t.Fatalf("Expected no event for an unchanged number of mails, but got: %v", event)
+	}
+	// 3) A mail has been expunged
+	event, ok = mc.parseUpdate(&imap.Response{Type: imap.Data, Label: "EXPUNGE",
+		Fields: []imap.Field{uint32(3), "EXPUNGE"}})
+	if !ok || event.Type != EXPUNGE_EVENT || event.Exists != 24 ||
+		!reflect.DeepEqual(event.SeqNbrs, []uint32{3}) {
+		t.Fatalf("Expected expunge event for sequence number 3, but got: %v", event)
+	}
+}
+
+func TestPublishEvents(t *testing.T) {
+	var mc *MailCon = &MailCon{}
+	listener := mc.AddEventListener()
+	mc.publish(MailEvent{Type: EXPUNGE_EVENT, Folder: "/"})
+	if event := <-listener; event.Type != EXPUNGE_EVENT {
+		t.Fatalf("Expected published expunge event, but got: %v", event)
+	}
+	mc.RemoveEventListener(listener)
+	if _, ok := <-listener; ok {
+		t.Fatal("Expected listener channel to be closed after removal")
+	}
+}
+
+func TestPublishNewMails(T *testing.T) {
+	t := mock.Server(T,
+		`S: * PREAUTH [CAPABILITY IMAP4rev1] Server ready`,
+	)
+	c, err := t.Dial()
+	t.Join(err)
+	var (
+		mc *MailCon = &MailCon{client: c, mailbox: DFLT_MAILBOX_NAME, delim: "/",
+			mutex: &imapMutex{}, uidStates: uidTracker{"/": {UIDValidity: 1, UIDNext: 4}},
+			selectedFolder: "/", lastExists: 3}
+		first  chan MailEvent = mc.AddEventListener()
+		second chan MailEvent = mc.AddEventListener()
+		header string         = "From: sender@mars.com\r\nSubject: Hello\r\n" +
+			"Date: Mon, 2 Jan 2006 15:04:05 +0000\r\n\r\n"
+	)
+	// 1) The server announces a new mail, which isn't published before its content is loaded
+	c.Data = append(c.Data, &imap.Response{Type: imap.Data, Label: "EXISTS",
+		Fields: []imap.Field{uint32(4), "EXISTS"}})
+	mc.dispatchUpdates()
+	select {
+	case event := <-first:
+		t.Fatalf("Expected no event before the new mail has been loaded, but got: %v", event)
+	default:
+	}
+	// 2) The new mail is loaded once and published to all listeners
+	t.Script(
+		`C: A1 SELECT "INBOX"`,
+		`S: * 4 EXISTS`,
+		`S: * OK [UIDVALIDITY 1] UIDs valid`,
+		`S: * OK [UIDNEXT 5] Predicted next UID`,
+		`S: A1 OK [READ-WRITE] SELECT completed`,
+		`C: A2 SELECT "INBOX"`,
+		`S: * 4 EXISTS`,
+		`S: * OK [UIDVALIDITY 1] UIDs valid`,
+		`S: * OK [UIDNEXT 5] Predicted next UID`,
+		`S: A2 OK [READ-WRITE] SELECT completed`,
+		`C: A3 UID FETCH 4:* (UID FLAGS INTERNALDATE RFC822.SIZE RFC822.HEADER RFC822.TEXT)`,
+		fmt.Sprintf(`S: * 4 FETCH (UID 4 FLAGS () RFC822.SIZE 100 RFC822.HEADER {%d}`,
+			len(header)),
+		mock.Send(header),
+		`S:  RFC822.TEXT {5}`,
+		`S: Hello)`,
+		`S: A3 OK Fetch completed`,
+	)
+	mc.publishNewMails()
+	for _, listener := range []chan MailEvent{first, second} {
+		select {
+		case event := <-listener:
+			if event.Type != NEW_MAIL_EVENT || event.Folder != "/" || 1 != len(event.Mails) ||
+				4 != event.Mails[0].UID {
+				t.Fatalf("Expected new mail event with the mail of UID 4, but got: %v", event)
+			}
+		default:
+			t.Fatal("Expected every listener to receive the new mail event")
+		}
+	}
+	// 3) The published mail is known => nothing left to publish
+	if 0 != len(mc.newMailFolders) {
+		t.Errorf("Expected no pending new mails, but got: %v", mc.newMailFolders)
+	}
+}
@@ -0,0 +1,140 @@
+package mail
+
+import (
+	"errors"
+	"fmt"
+	"github.com/mxk/go-imap/imap"
+	"strings"
+	"time"
+)
+
+////////////////////////////////////////////////////////////////////////////////////////////////////
+///									Public Expunge Methods										 ///
+////////////////////////////////////////////////////////////////////////////////////////////////////
+
+/**
+ * Permanently removes all mails of the given folder, which have their \Deleted flag set.
+ * @return The number of removed mails
+ */
+func (mc *MailCon) Expunge(folder string) (int, error) {
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+	if err := mc.selectFolder(folder, false); err != nil {
+		return 0, err
+	}
+	return mc.expunge_internal(nil)
+}
+
+/**
+ * Permanently deletes the mails with the given UIDs from the given folder, i.e., they are not
+ * moved to the Trash folder.
+ * ATTENTION: If the server doesn't support UIDPLUS, all other mails of the folder, which have their
+ * \Deleted flag set, are removed as well.
+ * @return The number of removed mails
+ */
+func (mc *MailCon) DeleteMails(folder string, uids []uint32) (int, error) {
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+	if 0 == len(uids) {
+		return 0, errors.New("No mails to be deleted given")
+	}
+	if err := mc.selectFolder(folder, false); err != nil {
+		return 0, err
+	}
+	set := new(imap.SeqSet)
+	set.AddNum(uids...)
+	return mc.deleteMails_internal(set)
+}
+
+/**
+ * Permanently deletes all mails of the given folder, e.g., to empty the Trash or Junk folder.
+ * @return The number of removed mails
+ */
+func (mc *MailCon) EmptyFolder(folder string) (int, error) {
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+	if err := mc.selectFolder(folder, false); err != nil {
+		return 0, err
+	}
+	if nil != mc.client.Mailbox && 0 == mc.client.Mailbox.Messages {
+		return 0, nil
+	}
+	set, _ := imap.NewSeqSet("1:*")
+	if _, err := mc.waitFor(mc.client.Store(set, "+FLAGS.SILENT",
+		SerializeFlags(&Flags{Deleted: true}))); err != nil {
+		return 0, err
+	}
+	return mc.expunge_internal(nil)
+}
+
+/**
+ * Permanently deletes all mails of the given folder, which have been received before the given
+ * date, e.g., to only keep the mails of the last 30 days in the Trash folder.
+ * @return The number of removed mails
+ */
+func (mc *MailCon) PurgeFolder(folder string, before time.Time) (int, error) {
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+	uids, err := mc.search_internal(folder, &SearchQuery{Before: before})
+	if err != nil || 0 == len(uids) {
+		return 0, err
+	}
+	set := new(imap.SeqSet)
+	set.AddNum(uids...)
+	return mc.deleteMails_internal(set)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////
+///									Private Expunge Methods										 ///
+////////////////////////////////////////////////////////////////////////////////////////////////////
+
+/**
+ * Sets the \Deleted flag of the given mails of the selected folder and expunges them.
+ * ATTENTION: DOES NOT LOCK THE IMAP CONNECTION! => Has to be wrapped into a mutex lock method
+ */
+func (mc *MailCon) deleteMails_internal(uids *imap.SeqSet) (int, error) {
+	if _, err := mc.waitFor(mc.client.UIDStore(uids, "+FLAGS.SILENT",
+		SerializeFlags(&Flags{Deleted: true}))); err != nil {
+		return 0, err
+	}
+	return mc.expunge_internal(uids)
+}
+
+/**
+ * Removes the mails of the selected folder, which have their \Deleted flag set. If UIDs are given
+ * and the server supports UIDPLUS, only those mails are removed (UID EXPUNGE), otherwise all
+ * mails flagged as deleted are removed (EXPUNGE).
+ * ATTENTION: DOES NOT LOCK THE IMAP CONNECTION! => Has to be wrapped into a mutex lock method
+ * @param uids The mails to be removed (nil = all mails flagged as deleted)
+ * @return The number of removed mails
+ */
+func (mc *MailCon) expunge_internal(uids *imap.SeqSet) (int, error) {
+	if !mc.client.Caps["UIDPLUS"] {
+		uids = nil
+	}
+	cmd, err := mc.waitFor(mc.client.Expunge(uids))
+	if err != nil {
+		return 0, fmt.Errorf("[watney] ERROR waiting for result of expunge command\n\t%s\n",
+			err.Error())
+	}
+	return mc.publishExpunges(cmd), nil
+}
+
+/**
+ * Publishes an EXPUNGE_EVENT for each mail, which has been removed by the given command.
+ * ATTENTION: DOES NOT LOCK THE IMAP CONNECTION! => Has to be wrapped into a mutex lock method
+ * @return The number of removed mails
+ */
+func (mc *MailCon) publishExpunges(cmd *imap.Command) int {
+	var removed int = 0
+	for _, resp := range cmd.Data {
+		if strings.ToUpper(resp.Label) != "EXPUNGE" {
+			continue
+		}
+		removed++
+		if event, ok := mc.parseUpdate(resp); ok {
+			mc.publish(event)
+		}
+	}
+	return removed
+}
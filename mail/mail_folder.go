@@ -0,0 +1,497 @@
+package mail
+
+import (
+	"errors"
+	"fmt"
+	"github.com/mxk/go-imap/imap"
+	"sort"
+	"strings"
+)
+
+// Represents one mailbox folder on the IMAP server, as returned by the LIST and LSUB commands
+type Folder struct {
+	// The name used to address this folder in all other MailCon methods ("/" = root, "Sent", ...)
+	Path string
+	// The complete name of the folder on the IMAP server, e.g., "INBOX.Sent"
+	ServerName string
+	// The last hierarchy level of the folder name, e.g., "Sent" (used for displaying the folder)
+	DisplayName string
+	// The hierarchy delimiter of this folder
+	Delim string
+	// All attributes the server returned for this folder, e.g., \Noselect, \HasChildren
+	Attributes []string
+	// Whether the folder can be selected (false if the folder has the \Noselect attribute)
+	Selectable bool
+	// Whether the server flagged the folder to contain sub folders
+	HasChildren bool
+	// Whether the user is subscribed to this folder (the folder was returned by LSUB)
+	Subscribed bool
+	// The special-use of this folder (RFC 6154), e.g., \Sent, \Trash or empty for regular folders
+	SpecialUse string
+	// All sub folders of this folder
+	Children []*Folder
+}
+
+// Special-use attributes of folders as defined in RFC 6154
+const (
+	SPECIAL_USE_SENT    string = "\\Sent"
+	SPECIAL_USE_TRASH   string = "\\Trash"
+	SPECIAL_USE_DRAFTS  string = "\\Drafts"
+	SPECIAL_USE_JUNK    string = "\\Junk"
+	SPECIAL_USE_ARCHIVE string = "\\Archive"
+)
+
+// The folders used for each special-use, if the server doesn't announce them:
+// special-use attribute -> folder path
+var dfltSpecialFolders map[string]string = map[string]string{
+	SPECIAL_USE_SENT:    "Sent",
+	SPECIAL_USE_TRASH:   "Trash",
+	SPECIAL_USE_DRAFTS:  "Drafts",
+	SPECIAL_USE_JUNK:    "Junk",
+	SPECIAL_USE_ARCHIVE: "Archive",
+}
+
+// Attributes used by the XLIST command (pre RFC 6154) that differ from their special-use name:
+// XLIST attribute -> special-use attribute
+var xlistSpecialUses map[string]string = map[string]string{
+	"\\Spam": SPECIAL_USE_JUNK,
+}
+
+// Used to enable sorting of folders by their path
+type FolderSlice []*Folder
+
+func (p FolderSlice) Len() int           { return len(p) }
+func (p FolderSlice) Less(i, j int) bool { return p[i].Path < p[j].Path }
+func (p FolderSlice) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+
+////////////////////////////////////////////////////////////////////////////////////////////////////
+///									Public Folder Methods										 ///
+////////////////////////////////////////////////////////////////////////////////////////////////////
+
+/**
+ * Retrieves all folders of the current user from the IMAP server (LIST) and marks those the user
+ * has subscribed to (LSUB).
+ * @return The folder hierarchy, whereas each returned root folder contains its sub folders.
+ */
+func (mc *MailCon) ListFolders() ([]*Folder, error) {
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+	var (
+		folders []*Folder
+		err     error
+	)
+	if folders, err = mc.listFolders_internal(); err != nil {
+		return nil, err
+	}
+	return mc.buildFolderTree(folders), nil
+}
+
+/**
+ * Returns the path of the folder used for the given special-use, e.g., SPECIAL_USE_SENT. If the
+ * server didn't announce a folder for this special-use, the default folder is returned ("Sent").
+ */
+func (mc *MailCon) SpecialFolder(specialUse string) string {
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+	return mc.specialFolder(specialUse)
+}
+
+/**
+ * Creates a new folder with the given name below the 'parent' folder and subscribes to it.
+ * @param parent The folder in which the new folder should be created ("/" = root)
+ * @param name The name of the new folder, which must not contain the server's delimiter
+ * @return The path of the newly created folder (to be used in all other MailCon methods)
+ */
+func (mc *MailCon) CreateFolder(parent, name string) (string, error) {
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+	if err := mc.checkFolderName(name); err != nil {
+		return "", err
+	}
+	var serverName string = fmt.Sprintf("%s%s%s", mc.mailboxName(parent), mc.delim, name)
+	if _, err := mc.waitFor(mc.client.Create(serverName)); err != nil {
+		return "", err
+	}
+	if _, err := mc.waitFor(mc.client.Subscribe(serverName)); err != nil {
+		return "", err
+	}
+	mc.refreshFolderNames()
+	return mc.folderPath(serverName), nil
+}
+
+/**
+ * Renames the given folder to 'newName', whereas the folder remains in its parent folder.
+ * @param folder The folder to be renamed, e.g., "Projects"
+ * @param newName The new name of the folder, which must not contain the server's delimiter
+ * @return The new path of the renamed folder
+ */
+func (mc *MailCon) RenameFolder(folder, newName string) (string, error) {
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+	if err := mc.checkModifiableFolder(folder); err != nil {
+		return "", err
+	}
+	if err := mc.checkFolderName(newName); err != nil {
+		return "", err
+	}
+	var (
+		serverName    string = mc.mailboxName(folder)
+		newServerName string = newName
+	)
+	if idx := strings.LastIndex(serverName, mc.delim); idx >= 0 {
+		newServerName = serverName[:idx+len(mc.delim)] + newName
+	}
+	if _, err := mc.waitFor(mc.client.Rename(serverName, newServerName)); err != nil {
+		return "", err
+	}
+	mc.refreshFolderNames()
+	return mc.folderPath(newServerName), nil
+}
+
+/**
+ * Deletes the given folder on the IMAP server and removes its subscription.
+ * ATTENTION: Depending on the server, all mails stored in the folder are deleted as well.
+ */
+func (mc *MailCon) DeleteFolder(folder string) error {
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+	if err := mc.checkModifiableFolder(folder); err != nil {
+		return err
+	}
+	var serverName string = mc.mailboxName(folder)
+	if _, err := mc.waitFor(mc.client.Delete(serverName)); err != nil {
+		return err
+	}
+	// Some servers remove the subscription together with the folder => ignore errors
+	mc.waitFor(mc.client.Unsubscribe(serverName))
+	mc.refreshFolderNames()
+	return nil
+}
+
+func (mc *MailCon) SubscribeFolder(folder string) error {
+	return mc.UpdateFolderSubscription(folder, true)
+}
+func (mc *MailCon) UnsubscribeFolder(folder string) error {
+	return mc.UpdateFolderSubscription(folder, false)
+}
+
+/**
+ * @param folder The folder whose subscription should be changed
+ * @param subscribe True  - Subscribes to the folder (SUBSCRIBE)
+ *					False - Removes the subscription of the folder (UNSUBSCRIBE)
+ */
+func (mc *MailCon) UpdateFolderSubscription(folder string, subscribe bool) error {
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+	var serverName string = mc.mailboxName(folder)
+	if subscribe {
+		_, err := mc.waitFor(mc.client.Subscribe(serverName))
+		return err
+	}
+	_, err := mc.waitFor(mc.client.Unsubscribe(serverName))
+	return err
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////
+///									Private Folder Methods										 ///
+////////////////////////////////////////////////////////////////////////////////////////////////////
+
+/**
+ * Retrieves the flat list of all folders and refreshes the mapping of folder paths to server
+ * mailbox names.
+ * ATTENTION: DOES NOT LOCK THE IMAP CONNECTION! => Has to be wrapped into a mutex lock method
+ */
+func (mc *MailCon) listFolders_internal() ([]*Folder, error) {
+	var (
+		cmd        *imap.Command
+		folders    []*Folder       = []*Folder{}
+		subscribed map[string]bool = make(map[string]bool)
+		err        error
+	)
+	// 1) Retrieve all folders the user subscribed to
+	if cmd, err = mc.waitFor(mc.client.LSub("", "*")); err != nil {
+		return nil, err
+	}
+	for _, resp := range cmd.Data {
+		if info := resp.MailboxInfo(); nil != info {
+			subscribed[info.Name] = true
+		}
+	}
+	// 2) Retrieve all existing folders (including their special-use attributes)
+	if cmd, err = mc.waitFor(mc.listAll()); err != nil {
+		return nil, err
+	}
+	for _, resp := range cmd.Data {
+		if info := mailboxInfo(resp); nil != info {
+			folder := mc.newFolder(info)
+			folder.Subscribed = subscribed[info.Name]
+			folders = append(folders, folder)
+		}
+	}
+	// 3) Clean the data queue
+	mc.clearData()
+	mc.updateFolderNames(folders)
+	mc.updateSpecialFolders(folders)
+	return folders, nil
+}
+
+/**
+ * Lists all folders using the most specific command the server supports to retrieve the
+ * special-use attributes: LIST RETURN (SPECIAL-USE), XLIST or a plain LIST (servers supporting
+ * SPECIAL-USE return the attributes for a plain LIST as well).
+ */
+func (mc *MailCon) listAll() (*imap.Command, error) {
+	switch {
+	// The RETURN option requires the extended LIST command (RFC 5258)
+	case mc.client.Caps["SPECIAL-USE"] && mc.client.Caps["LIST-EXTENDED"]:
+		return mc.client.Send("LIST", mc.client.Quote(""), mc.client.Quote("*"), "RETURN",
+			[]imap.Field{"SPECIAL-USE"})
+	case mc.client.Caps["XLIST"]:
+		return mc.client.Send("XLIST", mc.client.Quote(""), mc.client.Quote("*"))
+	default:
+		return mc.client.List("", "*")
+	}
+}
+
+/**
+ * Remembers the folder path for each special-use announced by the server. The first announced
+ * folder wins, if a special-use is used for several folders.
+ */
+func (mc *MailCon) updateSpecialFolders(folders []*Folder) {
+	mc.specialFolders = make(map[string]string, len(dfltSpecialFolders))
+	for _, folder := range folders {
+		if len(folder.SpecialUse) == 0 {
+			continue
+		}
+		if _, ok := mc.specialFolders[folder.SpecialUse]; !ok {
+			mc.specialFolders[folder.SpecialUse] = folder.Path
+		}
+	}
+}
+
+/**
+ * ATTENTION: DOES NOT LOCK THE IMAP CONNECTION! => Has to be wrapped into a mutex lock method
+ */
+func (mc *MailCon) specialFolder(specialUse string) string {
+	if folder, ok := mc.specialFolders[specialUse]; ok {
+		return folder
+	}
+	return dfltSpecialFolders[specialUse]
+}
+
+/**
+ * Remembers the server mailbox name for each of the given folders, so it can be resolved from the
+ * folder path later on (see mailboxName).
+ * If a path is ambiguous (e.g., "INBOX.Sent" and "Sent" both exist), the folder located below the
+ * user's mailbox wins.
+ */
+func (mc *MailCon) updateFolderNames(folders []*Folder) {
+	mc.folderNames = make(map[string]string, len(folders))
+	for _, folder := range folders {
+		if existing, ok := mc.folderNames[folder.Path]; ok && existing != folder.ServerName &&
+			strings.HasPrefix(existing, mc.mailbox+mc.delim) {
+			continue
+		}
+		mc.folderNames[folder.Path] = folder.ServerName
+	}
+}
+
+/**
+ * Reloads the folder list to update the folder path mapping, e.g., after a folder was created.
+ * ATTENTION: DOES NOT LOCK THE IMAP CONNECTION! => Has to be wrapped into a mutex lock method
+ */
+func (mc *MailCon) refreshFolderNames() {
+	if _, err := mc.listFolders_internal(); err != nil {
+		mc.logMC(fmt.Sprintf("Couldn't refresh folder list: %s", err.Error()), imap.LogAll)
+	}
+}
+
+/**
+ * Checks whether the given name can be used as a (single level) folder name.
+ */
+func (mc *MailCon) checkFolderName(name string) error {
+	if 0 == len(strings.TrimSpace(name)) {
+		return errors.New("Folder name must not be empty")
+	}
+	if len(mc.delim) > 0 && strings.Contains(name, mc.delim) {
+		return fmt.Errorf("Folder name '%s' must not contain the delimiter '%s'", name, mc.delim)
+	}
+	return nil
+}
+
+/**
+ * Checks whether the given folder may be renamed or deleted (which is not the case for the root).
+ */
+func (mc *MailCon) checkModifiableFolder(folder string) error {
+	if 0 == len(folder) || folder == "/" || strings.EqualFold(folder, mc.mailbox) {
+		return fmt.Errorf("The root folder '%s' can't be renamed or deleted", mc.mailbox)
+	}
+	return nil
+}
+
+func (mc *MailCon) newFolder(info *imap.MailboxInfo) *Folder {
+	var (
+		attrs       []string = make([]string, 0, len(info.Attrs))
+		displayName string   = info.Name
+	)
+	for attr, set := range info.Attrs {
+		if set {
+			attrs = append(attrs, attr)
+		}
+	}
+	sort.Strings(attrs)
+	if len(info.Delim) > 0 {
+		displayName = info.Name[strings.LastIndex(info.Name, info.Delim)+len(info.Delim):]
+	}
+	return &Folder{
+		Path:        mc.folderPath(info.Name),
+		ServerName:  info.Name,
+		DisplayName: displayName,
+		Delim:       info.Delim,
+		Attributes:  attrs,
+		Selectable:  !hasAttribute(attrs, "\\Noselect") && !hasAttribute(attrs, "\\NonExistent"),
+		HasChildren: hasAttribute(attrs, "\\HasChildren"),
+		SpecialUse:  specialUse(attrs),
+	}
+}
+
+/**
+ * @return The special-use attribute (RFC 6154) contained in the given folder attributes, or an
+ *		   empty string, if the folder is a regular folder.
+ */
+func specialUse(attrs []string) string {
+	for use := range dfltSpecialFolders {
+		if hasAttribute(attrs, use) {
+			return use
+		}
+	}
+	for xlistAttr, use := range xlistSpecialUses {
+		if hasAttribute(attrs, xlistAttr) {
+			return use
+		}
+	}
+	return ""
+}
+
+/**
+ * Extracts the folder information of a LIST, LSUB or XLIST response.
+ * @return The folder information or nil, if the response is none of the above
+ */
+func mailboxInfo(resp *imap.Response) *imap.MailboxInfo {
+	if info := resp.MailboxInfo(); nil != info {
+		return info
+	}
+	// The XLIST response has the same layout as the LIST response:
+	// [0] XLIST | [1] attributes | [2] delimiter | [3] folder name
+	if f := resp.Fields; len(f) == 4 && strings.EqualFold(imap.AsAtom(f[0]), "XLIST") {
+		return &imap.MailboxInfo{
+			Attrs: imap.AsFlagSet(f[1]),
+			Delim: imap.AsString(f[2]),
+			Name:  imap.AsString(f[3]),
+		}
+	}
+	return nil
+}
+
+/**
+ * Converts the given server mailbox name into the folder path used by all MailCon methods:
+ *  - "INBOX" -> "/"
+ *  - "INBOX.Sent" -> "Sent"
+ *  - "Archive" -> "Archive" (folders outside of the user's mailbox keep their name)
+ */
+func (mc *MailCon) folderPath(serverName string) string {
+	if strings.EqualFold(serverName, mc.mailbox) {
+		return "/"
+	}
+	if prefix := mc.mailbox + mc.delim; strings.HasPrefix(serverName, prefix) {
+		return strings.TrimPrefix(serverName, prefix)
+	}
+	return serverName
+}
+
+/**
+ * Converts the given folder path into the mailbox name on the IMAP server. Folders that have been
+ * retrieved via ListFolders are resolved to their actual server name. The default names of
+ * special-use folders ("Sent", "Trash", ...) are resolved to the folder announced by the server.
+ * All other folders are assumed to be located below the user's mailbox.
+ */
+func (mc *MailCon) mailboxName(folder string) string {
+	if 0 == len(folder) || folder == "/" {
+		return mc.mailbox
+	}
+	if serverName, ok := mc.folderNames[folder]; ok {
+		return serverName
+	}
+	for use, dfltFolder := range dfltSpecialFolders {
+		if specialFolder, ok := mc.specialFolders[use]; ok && folder == dfltFolder {
+			if serverName, ok := mc.folderNames[specialFolder]; ok {
+				return serverName
+			}
+		}
+	}
+	return fmt.Sprintf("%s%s%s", mc.mailbox, mc.delim, folder)
+}
+
+/**
+ * Builds the folder hierarchy from the flat list of folders by splitting the server names at
+ * their delimiter. Parent folders that haven't been returned by the server are added as
+ * non-selectable folders.
+ */
+func (mc *MailCon) buildFolderTree(folders []*Folder) []*Folder {
+	var (
+		byName map[string]*Folder = make(map[string]*Folder, len(folders))
+		roots  []*Folder          = []*Folder{}
+	)
+	for _, folder := range folders {
+		byName[folder.ServerName] = folder
+	}
+	var attach func(folder *Folder)
+	attach = func(folder *Folder) {
+		var idx int = -1
+		if len(folder.Delim) > 0 {
+			idx = strings.LastIndex(folder.ServerName, folder.Delim)
+		}
+		if idx <= 0 {
+			roots = append(roots, folder)
+			return
+		}
+		parentName := folder.ServerName[:idx]
+		parent, ok := byName[parentName]
+		if !ok {
+			// The server didn't list the parent => add a placeholder
+			parent = &Folder{
+				Path:        mc.folderPath(parentName),
+				ServerName:  parentName,
+				DisplayName: parentName[strings.LastIndex(parentName, folder.Delim)+len(folder.Delim):],
+				Delim:       folder.Delim,
+				Attributes:  []string{"\\Noselect"},
+				Selectable:  false,
+			}
+			byName[parentName] = parent
+			attach(parent)
+		}
+		parent.HasChildren = true
+		parent.Children = append(parent.Children, folder)
+	}
+	for _, folder := range folders {
+		attach(folder)
+	}
+	sortFolderTree(roots)
+	return roots
+}
+
+func sortFolderTree(folders []*Folder) {
+	sort.Sort(FolderSlice(folders))
+	for _, folder := range folders {
+		sortFolderTree(folder.Children)
+	}
+}
+
+func hasAttribute(attrs []string, attr string) bool {
+	for _, cur := range attrs {
+		if strings.EqualFold(cur, attr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,149 @@
+package mail
+
+import (
+	"github.com/mxk/go-imap/mock"
+	"testing"
+)
+
+func TestBuildFolderTree(t *testing.T) {
+	var mc *MailCon = &MailCon{mailbox: DFLT_MAILBOX_NAME, delim: "."}
+	folders := []*Folder{
+		{ServerName: "INBOX", Path: mc.folderPath("INBOX"), Delim: ".", Selectable: true},
+		{ServerName: "INBOX.Sent", Path: mc.folderPath("INBOX.Sent"), Delim: ".", Selectable: true},
+		{ServerName: "INBOX.Lists.golang", Path: mc.folderPath("INBOX.Lists.golang"), Delim: ".",
+			Selectable: true},
+		{ServerName: "Archive", Path: mc.folderPath("Archive"), Delim: ".", Selectable: true},
+	}
+	roots := mc.buildFolderTree(folders)
+	// 1) Expect INBOX and Archive as root folders (sorted by path)
+	if 2 != len(roots) || roots[0].Path != "/" || roots[1].Path != "Archive" {
+		t.Fatalf("Expected root folders '/' and 'Archive', but got: %v", roots)
+	}
+	// 2) Expect INBOX to contain 'Lists' (placeholder) and 'Sent'
+	inbox := roots[0]
+	if 2 != len(inbox.Children) || inbox.Children[0].Path != "Lists" ||
+		inbox.Children[1].Path != "Sent" {
+		t.Fatalf("Expected INBOX sub folders 'Lists' and 'Sent', but got: %v", inbox.Children)
+	}
+	lists := inbox.Children[0]
+	if lists.Selectable || !lists.HasChildren || lists.DisplayName != "Lists" {
+		t.Fatalf("Expected 'Lists' to be a non-selectable placeholder with children: %v", lists)
+	}
+	if 1 != len(lists.Children) || lists.Children[0].Path != "Lists.golang" {
+		t.Fatalf("Expected 'Lists' to contain 'Lists.golang', but got: %v", lists.Children)
+	}
+}
+
+func TestMailboxName(t *testing.T) {
+	var mc *MailCon = &MailCon{mailbox: DFLT_MAILBOX_NAME, delim: "."}
+	mc.updateFolderNames([]*Folder{
+		{ServerName: "Sent", Path: mc.folderPath("Sent")},
+		{ServerName: "INBOX.Sent", Path: mc.folderPath("INBOX.Sent")},
+		{ServerName: "Archive", Path: mc.folderPath("Archive")},
+	})
+	for folder, expected := range map[string]string{
+		"/":        "INBOX",
+		"":         "INBOX",
+		"Sent":     "INBOX.Sent",
+		"Archive":  "Archive",
+		"Projects": "INBOX.Projects",
+	} {
+		if name := mc.mailboxName(folder); name != expected {
+			t.Errorf("Expected folder '%s' to resolve to '%s', but got '%s'", folder, expected, name)
+		}
+	}
+}
+
+func TestCheckFolderName(t *testing.T) {
+	var mc *MailCon = &MailCon{mailbox: DFLT_MAILBOX_NAME, delim: "."}
+	if err := mc.checkFolderName("Projects"); err != nil {
+		t.Errorf("Expected 'Projects' to be a valid folder name: %s", err.Error())
+	}
+	for _, invalid := range []string{"", "  ", "Projects.2015"} {
+		if err := mc.checkFolderName(invalid); err == nil {
+			t.Errorf("Expected '%s' to be an invalid folder name", invalid)
+		}
+	}
+	if err := mc.checkModifiableFolder("/"); err == nil {
+		t.Error("Expected the root folder not to be modifiable")
+	}
+}
+
+func TestSpecialFolders(t *testing.T) {
+	var mc *MailCon = &MailCon{mailbox: DFLT_MAILBOX_NAME, delim: "/"}
+	folders := []*Folder{
+		{ServerName: "INBOX", Path: mc.folderPath("INBOX")},
+		{ServerName: "[Gmail]/Sent Mail", Path: mc.folderPath("[Gmail]/Sent Mail"),
+			SpecialUse: specialUse([]string{"\\HasNoChildren", "\\Sent"})},
+		{ServerName: "[Gmail]/Spam", Path: mc.folderPath("[Gmail]/Spam"),
+			SpecialUse: specialUse([]string{"\\Spam"})},
+	}
+	mc.updateFolderNames(folders)
+	mc.updateSpecialFolders(folders)
+	// 1) Announced special-use folders are used, all others fall back to their default
+	if folder := mc.specialFolder(SPECIAL_USE_SENT); folder != "[Gmail]/Sent Mail" {
+		t.Errorf("Expected sent folder '[Gmail]/Sent Mail', but got '%s'", folder)
+	}
+	if folder := mc.specialFolder(SPECIAL_USE_JUNK); folder != "[Gmail]/Spam" {
+		t.Errorf("Expected junk folder '[Gmail]/Spam', but got '%s'", folder)
+	}
+	if folder := mc.specialFolder(SPECIAL_USE_TRASH); folder != "Trash" {
+		t.Errorf("Expected default trash folder 'Trash', but got '%s'", folder)
+	}
+	// 2) The default folder names are resolved to the announced special-use folders
+	if name := mc.mailboxName("Sent"); name != "[Gmail]/Sent Mail" {
+		t.Errorf("Expected 'Sent' to resolve to '[Gmail]/Sent Mail', but got '%s'", name)
+	}
+	if name := mc.mailboxName("Trash"); name != "INBOX/Trash" {
+		t.Errorf("Expected 'Trash' to resolve to 'INBOX/Trash', but got '%s'", name)
+	}
+}
+
+func TestListSpecialFolders(T *testing.T) {
+	// 1) XLIST responses are collected by the XLIST command
+	t := mock.Server(T,
+		`S: * PREAUTH [CAPABILITY IMAP4rev1 XLIST] Server ready`,
+	)
+	c, err := t.Dial()
+	t.Join(err)
+	registerCommands(c)
+	var mc *MailCon = &MailCon{client: c, mailbox: DFLT_MAILBOX_NAME, delim: "/"}
+	t.Script(
+		`C: A1 LSUB "" "*"`,
+		`S: A1 OK LSUB completed`,
+		`C: A2 XLIST "" "*"`,
+		`S: * XLIST (\HasNoChildren \Inbox) "/" "INBOX"`,
+		`S: * XLIST (\HasNoChildren \Sent) "/" "[Gmail]/Sent Mail"`,
+		`S: A2 OK XLIST completed`,
+	)
+	folders, err := mc.listFolders_internal()
+	t.Join(err)
+	if len(folders) != 2 || mc.specialFolder(SPECIAL_USE_SENT) != "[Gmail]/Sent Mail" {
+		t.Errorf("Special-use folders of XLIST haven't been detected: %v", mc.specialFolders)
+	}
+	// 2) The RETURN option is only sent, if the server supports the extended LIST command
+	for caps, list := range map[string]string{
+		"SPECIAL-USE":               `C: A2 LIST "" "*"`,
+		"SPECIAL-USE LIST-EXTENDED": `C: A2 LIST "" "*" RETURN (SPECIAL-USE)`,
+	} {
+		t := mock.Server(T,
+			`S: * PREAUTH [CAPABILITY IMAP4rev1 `+caps+`] Server ready`,
+		)
+		c, err := t.Dial()
+		t.Join(err)
+		registerCommands(c)
+		var mc *MailCon = &MailCon{client: c, mailbox: DFLT_MAILBOX_NAME, delim: "/"}
+		t.Script(
+			`C: A1 LSUB "" "*"`,
+			`S: A1 OK LSUB completed`,
+			list,
+			`S: * LIST (\HasNoChildren \Junk) "/" "Spam"`,
+			`S: A2 OK LIST completed`,
+		)
+		_, err = mc.listFolders_internal()
+		t.Join(err)
+		if folder := mc.specialFolder(SPECIAL_USE_JUNK); folder != "Spam" {
+			t.Errorf("Expected junk folder 'Spam' for %s, but got '%s'", caps, folder)
+		}
+	}
+}
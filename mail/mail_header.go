@@ -0,0 +1,277 @@
+package mail
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/mxk/go-imap/imap"
+	"net/textproto"
+	"strings"
+)
+
+// Results of the authentication methods SPF, DKIM and DMARC (RFC 8601)
+const (
+	AUTH_PASS      string = "pass"
+	AUTH_FAIL      string = "fail"
+	AUTH_SOFTFAIL  string = "softfail"
+	AUTH_NEUTRAL   string = "neutral"
+	AUTH_NONE      string = "none" // The method hasn't been applied (or its result is unknown)
+	AUTH_POLICY    string = "policy"
+	AUTH_TEMPERROR string = "temperror"
+	AUTH_PERMERROR string = "permerror"
+)
+
+// The trust levels of a mail derived from its authentication results (see Authenticity)
+const (
+	TRUST_VERIFIED   string = "verified"   // The sender domain has been verified
+	TRUST_SUSPICIOUS string = "suspicious" // At least one authentication method failed
+	TRUST_UNKNOWN    string = "unknown"    // The mail couldn't be verified
+)
+
+// A single field of the mail header as it appears in the mail
+type HeaderField struct {
+	// The name of the field, e.g., Received
+	Name string
+	// The unfolded, but otherwise raw value of the field (encoded words aren't decoded)
+	Value string
+}
+
+// The summary of the authentication results of a mail (Authentication-Results:, Received-SPF:),
+// e.g., to show whether the sender of the mail can be trusted
+type Authenticity struct {
+	// The SPF result for the sending server (one of the AUTH_* constants)
+	SPF string
+	// The DKIM result for the signature of the mail (one of the AUTH_* constants)
+	DKIM string
+	// The DMARC result for the domain of the From: address (one of the AUTH_* constants)
+	DMARC string
+	// The domain of a DKIM signature, that has been verified by the trusted server (empty, if the
+	// mail isn't signed or no signature passed)
+	DKIMDomain string
+	// The server, which checked the mail (empty, if no Authentication-Results: are given)
+	AuthServID string
+	// The trust level derived from all results (one of the TRUST_* constants)
+	Trust string
+}
+
+// All valid results of the authentication methods
+var authResults map[string]bool = map[string]bool{
+	AUTH_PASS: true, AUTH_FAIL: true, AUTH_SOFTFAIL: true, AUTH_NEUTRAL: true, AUTH_NONE: true,
+	AUTH_POLICY: true, AUTH_TEMPERROR: true, AUTH_PERMERROR: true,
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////
+///									Public Header Methods										 ///
+////////////////////////////////////////////////////////////////////////////////////////////////////
+
+/**
+ * Loads all fields of the header of a mail in their original order including duplicates, e.g.,
+ * to show all Received: fields of the mail. The \Seen flag of the mail isn't changed.
+ */
+func (mc *MailCon) LoadHeaderFields(folder string, uid uint32) ([]HeaderField, error) {
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+	if err := mc.selectFolder(folder, true); err != nil {
+		return nil, err
+	}
+	var (
+		cmd    *imap.Command
+		header []byte
+		err    error
+	)
+	set, _ := imap.NewSeqSet(fmt.Sprintf("%d", uid))
+	if cmd, err = mc.waitFor(mc.client.UIDFetch(set, "BODY.PEEK[HEADER]")); err != nil {
+		return nil, err
+	}
+	for _, resp := range cmd.Data {
+		if info := resp.MessageInfo(); nil != info && info.UID == uid {
+			header = imap.AsBytes(info.Attrs["BODY[HEADER]"])
+		}
+	}
+	mc.clearData()
+	if nil == header {
+		return nil, fmt.Errorf("No mail found for the given ID: %d", uid)
+	}
+	return parseHeaderFields(header), nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////
+///									Private Header Methods										 ///
+////////////////////////////////////////////////////////////////////////////////////////////////////
+
+/**
+ * Splits the given raw header into its fields and unfolds their values. The header ends with the
+ * first empty line, lines without a colon are skipped.
+ */
+func parseHeaderFields(header []byte) []HeaderField {
+	var fields []HeaderField = []HeaderField{}
+	for _, line := range strings.Split(string(bytes.Replace(header, []byte("\r\n"), []byte("\n"),
+		-1)), "\n") {
+		if 0 == len(line) {
+			break
+		}
+		// 1) Continuation lines belong to the previous field (RFC 5322, 2.2.3)
+		if ' ' == line[0] || '\t' == line[0] {
+			if len(fields) > 0 {
+				fields[len(fields)-1].Value += " " + strings.TrimSpace(line)
+			}
+			continue
+		}
+		if colon := strings.Index(line, ":"); colon > 0 {
+			fields = append(fields, HeaderField{
+				Name:  strings.TrimSpace(line[:colon]),
+				Value: strings.TrimSpace(line[colon+1:]),
+			})
+		}
+	}
+	return fields
+}
+
+/**
+ * Summarizes the authentication results of the given header. Authentication-Results: fields can be
+ * added by every server on the way and by the sender itself, so only the topmost field is used,
+ * which has been added by the receiving server. If authserv-ids are given, the topmost field of
+ * one of these servers is used instead. All other fields are ignored, as they could be forged.
+ * The same applies to Received-SPF: fields, which are only used, if the trusted field contains no
+ * SPF result.
+ * @param authServIDs The authserv-ids of the trusted servers (empty = the topmost field is trusted)
+ */
+func parseAuthenticity(header textproto.MIMEHeader, authServIDs []string) Authenticity {
+	var auth Authenticity
+	// 1) Authentication-Results: authserv-id; spf=pass smtp.mailfrom=...; dkim=pass header.d=...
+	for _, field := range header["Authentication-Results"] {
+		var (
+			statements []string = strings.Split(removeComments(field), ";")
+			words      []string = strings.Fields(statements[0])
+		)
+		if len(words) > 0 && isTrustedAuthServ(words[0], authServIDs) {
+			auth.AuthServID = words[0]
+			readAuthResults(&auth, statements[1:])
+			break
+		} else if 0 == len(authServIDs) {
+			// The topmost field is malformed
+			break
+		}
+	}
+	// 2) Received-SPF: pass (mars.com: domain of ...) client-ip=... (topmost field only)
+	if 0 == len(auth.SPF) {
+		if words := strings.Fields(removeComments(header.Get("Received-SPF"))); len(words) > 0 &&
+			authResults[strings.ToLower(words[0])] {
+			auth.SPF = strings.ToLower(words[0])
+		}
+	}
+	// ATTENTION: The d= tag of DKIM-Signature: isn't used, since it can't be verified without DNS
+	for _, result := range []*string{&auth.SPF, &auth.DKIM, &auth.DMARC} {
+		if 0 == len(*result) {
+			*result = AUTH_NONE
+		}
+	}
+	auth.Trust = trustLevel(auth)
+	return auth
+}
+
+/**
+ * @return Whether the given authserv-id is one of the given ids (all ids are trusted, if none are
+ *		   given)
+ */
+func isTrustedAuthServ(authServID string, authServIDs []string) bool {
+	if 0 == len(authServIDs) {
+		return true
+	}
+	for _, trusted := range authServIDs {
+		if strings.EqualFold(strings.TrimSpace(trusted), authServID) {
+			return true
+		}
+	}
+	return false
+}
+
+/**
+ * Reads the results of SPF, DKIM and DMARC from the given statements of one
+ * Authentication-Results: field, e.g., ["spf=pass smtp.mailfrom=mars.com", "dkim=fail ..."]
+ */
+func readAuthResults(auth *Authenticity, statements []string) {
+	for _, statement := range statements {
+		words := strings.Fields(statement)
+		if 0 == len(words) {
+			continue
+		}
+		parts := strings.SplitN(words[0], "=", 2)
+		if len(parts) != 2 || !authResults[strings.ToLower(parts[1])] {
+			continue
+		}
+		var (
+			// Methods may have a version, e.g., "dkim/1"
+			method string = strings.ToLower(strings.SplitN(parts[0], "/", 2)[0])
+			result string = strings.ToLower(parts[1])
+			target *string
+		)
+		switch method {
+		case "spf":
+			target = &auth.SPF
+		case "dkim":
+			target = &auth.DKIM
+		case "dmarc":
+			target = &auth.DMARC
+		default:
+			continue
+		}
+		// A mail may have several DKIM signatures => one valid signature is sufficient
+		if 0 == len(*target) || AUTH_PASS == result {
+			*target = result
+			// Only a verified signature proves the domain of the signer
+			if "dkim" == method && AUTH_PASS == result {
+				auth.DKIMDomain = propertyValue(words[1:], "header.d")
+			}
+		}
+	}
+}
+
+/**
+ * @return TRUST_VERIFIED, if DMARC or both SPF and DKIM passed | TRUST_SUSPICIOUS, if any method
+ *		   failed | TRUST_UNKNOWN, otherwise
+ */
+func trustLevel(auth Authenticity) string {
+	switch {
+	case AUTH_PASS == auth.DMARC || (AUTH_PASS == auth.SPF && AUTH_PASS == auth.DKIM):
+		return TRUST_VERIFIED
+	case AUTH_FAIL == auth.DMARC || AUTH_FAIL == auth.DKIM || AUTH_FAIL == auth.SPF ||
+		AUTH_SOFTFAIL == auth.SPF:
+		return TRUST_SUSPICIOUS
+	}
+	return TRUST_UNKNOWN
+}
+
+/**
+ * @return The value of the given property of an authentication result, e.g.,
+ *		   ["header.d=mars.com", "header.s=sel"], "header.d" -> "mars.com"
+ */
+func propertyValue(properties []string, name string) string {
+	for _, property := range properties {
+		if parts := strings.SplitN(property, "=", 2); len(parts) == 2 &&
+			strings.EqualFold(parts[0], name) {
+			return strings.Trim(parts[1], `"`)
+		}
+	}
+	return ""
+}
+
+/**
+ * @return The given header value without comments, e.g., "pass (good signature)" -> "pass "
+ */
+func removeComments(value string) string {
+	var (
+		buf   bytes.Buffer
+		depth int
+	)
+	for _, r := range value {
+		switch {
+		case '(' == r:
+			depth++
+		case ')' == r && depth > 0:
+			depth--
+		case 0 == depth:
+			buf.WriteRune(r)
+		}
+	}
+	return buf.String()
+}
@@ -0,0 +1,62 @@
+package mail
+
+import (
+	"net/textproto"
+	"reflect"
+	"testing"
+)
+
+func TestParseHeaderFields(t *testing.T) {
+	fields := parseHeaderFields([]byte("Received: from a.mars.com\r\n\tby b.mars.com\r\n" +
+		"Received: from c.mars.com\r\nSubject: Hi\r\n\r\nBody: no header"))
+	expected := []HeaderField{{"Received", "from a.mars.com by b.mars.com"},
+		{"Received", "from c.mars.com"}, {"Subject", "Hi"}}
+	if !reflect.DeepEqual(fields, expected) {
+		t.Errorf("Expected header fields %v, but got %v", expected, fields)
+	}
+}
+
+func TestParseAuthenticity(t *testing.T) {
+	for _, test := range []struct {
+		header      textproto.MIMEHeader
+		authServIDs []string
+		expected    Authenticity
+	}{
+		{textproto.MIMEHeader{"Authentication-Results": {"mx.mars.com; spf=pass " +
+			"smtp.mailfrom=nasa.gov; dkim=fail (bad signature) header.d=x.com; dkim=pass " +
+			"header.d=nasa.gov; dmarc=pass (p=REJECT) header.from=nasa.gov",
+			"evil.com; spf=fail; dmarc=fail"}}, nil,
+			Authenticity{SPF: AUTH_PASS, DKIM: AUTH_PASS, DMARC: AUTH_PASS, DKIMDomain: "nasa.gov",
+				AuthServID: "mx.mars.com", Trust: TRUST_VERIFIED}},
+		// Only the topmost Received-SPF: is used and the unverified DKIM-Signature: is ignored
+		{textproto.MIMEHeader{"Received-Spf": {"Pass (mars.com: designates 1.2.3.4)", "Fail"},
+			"Dkim-Signature": {"v=1; a=rsa-sha256; d=mars.com; s=sel; b=abc"}}, nil,
+			Authenticity{SPF: AUTH_PASS, DKIM: AUTH_NONE, DMARC: AUTH_NONE,
+				Trust: TRUST_UNKNOWN}},
+		// The SPF result of the trusted field takes precedence over Received-SPF:
+		{textproto.MIMEHeader{"Authentication-Results": {"mx.mars.com; spf=fail; dkim=fail " +
+			"header.d=nasa.gov"}, "Received-Spf": {"pass"}}, nil,
+			Authenticity{SPF: AUTH_FAIL, DKIM: AUTH_FAIL, DMARC: AUTH_NONE,
+				AuthServID: "mx.mars.com", Trust: TRUST_SUSPICIOUS}},
+		{textproto.MIMEHeader{"Authentication-Results": {"mx.mars.com; none"}}, nil,
+			Authenticity{SPF: AUTH_NONE, DKIM: AUTH_NONE, DMARC: AUTH_NONE,
+				AuthServID: "mx.mars.com", Trust: TRUST_UNKNOWN}},
+		// A forged lower field must not fill the results missing in the topmost field
+		{textproto.MIMEHeader{"Authentication-Results": {"mx.mars.com; spf=softfail " +
+			"smtp.mailfrom=nasa.gov", "mx.mars.com; dkim=pass header.d=nasa.gov; dmarc=pass"}},
+			nil, Authenticity{SPF: AUTH_SOFTFAIL, DKIM: AUTH_NONE, DMARC: AUTH_NONE,
+				AuthServID: "mx.mars.com", Trust: TRUST_SUSPICIOUS}},
+		// Only the topmost field of a trusted server is used
+		{textproto.MIMEHeader{"Authentication-Results": {"relay.mars.com; dmarc=fail",
+			"mx.mars.com; dmarc=pass", "mx.mars.com; spf=pass; dkim=pass"}},
+			[]string{"MX.mars.com"}, Authenticity{SPF: AUTH_NONE, DKIM: AUTH_NONE,
+				DMARC: AUTH_PASS, AuthServID: "mx.mars.com", Trust: TRUST_VERIFIED}},
+		{textproto.MIMEHeader{"Authentication-Results": {"evil.com; dmarc=pass"}},
+			[]string{"mx.mars.com"}, Authenticity{SPF: AUTH_NONE, DKIM: AUTH_NONE,
+				DMARC: AUTH_NONE, Trust: TRUST_UNKNOWN}},
+	} {
+		if auth := parseAuthenticity(test.header, test.authServIDs); auth != test.expected {
+			t.Errorf("Expected %v, but got %v", test.expected, auth)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package mail
+
+import (
+	"fmt"
+	"github.com/mxk/go-imap/imap"
+	"strings"
+)
+
+// The URL scheme used by HTML parts to reference other parts of the mail by their Content-ID
+// (RFC 2392), e.g., <img src="cid:logo@mars.com">
+const CID_SCHEME string = "cid:"
+
+// Media-types of inline parts, which are served to the browser (all other types could contain
+// active content)
+var inlineImageTypes map[string]bool = map[string]bool{
+	"image/bmp": true, "image/gif": true, "image/jpeg": true, "image/jpg": true, "image/png": true,
+	"image/webp": true,
+}
+
+/**
+ * @return The leaf part with the given Content-ID in the tree of this part or nil, if there is no
+ *		   such part
+ */
+func (p *MailPart) FindContentID(contentID string) *MailPart {
+	if nil == p || 0 == len(contentID) {
+		return nil
+	}
+	if 0 == len(p.Children) && p.ContentID == contentID {
+		return p
+	}
+	for _, child := range p.Children {
+		if found := child.FindContentID(contentID); nil != found {
+			return found
+		}
+	}
+	return nil
+}
+
+/**
+ * @return Whether this part is an image, that is referenced by the HTML part of the mail (usually
+ *		   within a multipart/related)
+ */
+func (p *MailPart) IsInlineImage() bool {
+	return len(p.ContentID) > 0 && inlineImageTypes[strings.ToLower(p.ContentType)]
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////
+///									Public Inline Methods										 ///
+////////////////////////////////////////////////////////////////////////////////////////////////////
+
+/**
+ * Loads the inline image with the given Content-ID of a mail, e.g., to show the images of an HTML
+ * newsletter or signature.
+ * @param contentID The Content-ID of the part without angle brackets or "cid:"
+ * @return The MIME information of the image part and its decoded body
+ */
+func (mc *MailCon) LoadInlinePart(folder string, uid uint32, contentID string) (*MailPart, []byte,
+	error) {
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+	set, _ := imap.NewSeqSet(fmt.Sprintf("%d", uid))
+	mails, err := mc.loadMails(set, folder, true, mc.client.UIDFetch)
+	if err != nil {
+		return nil, nil, err
+	} else if 0 == len(mails) {
+		return nil, nil, fmt.Errorf("No mail found for the given ID: %d", uid)
+	}
+	var part *MailPart = mails[0].Parts.FindContentID(contentID)
+	if nil == part || !part.IsInlineImage() {
+		return nil, nil, fmt.Errorf("Mail %d in folder '%s' has no inline image '%s'", uid, folder,
+			contentID)
+	}
+	body, err := decodeBody(part.Encoding, []byte(part.body))
+	if err != nil {
+		return nil, nil, err
+	}
+	return part, body, nil
+}
+
+/**
+ * @return A function, which maps the Content-IDs of the inline images of this mail to the URL
+ *		   returned by 'url' (e.g., a download URL of the web server), and all other Content-IDs to
+ *		   an empty string (see SanitizeHTML)
+ */
+func (m *Mail) InlineURLs(url func(contentID string) string) func(contentID string) string {
+	return func(contentID string) string {
+		if part := m.Parts.FindContentID(contentID); nil != part && part.IsInlineImage() {
+			return url(contentID)
+		}
+		return ""
+	}
+}
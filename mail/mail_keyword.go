@@ -0,0 +1,100 @@
+package mail
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Keywords, which are commonly used by mail clients (see RFC 5788)
+const (
+	JUNK_KEYWORD     string = "$Junk"    // The mail has been classified as spam
+	NOT_JUNK_KEYWORD string = "$NotJunk" // The mail has been classified as no spam
+	MDN_SENT_KEYWORD string = "$MDNSent" // A read receipt has been sent for the mail
+)
+
+// PERMANENTFLAGS entry, which states that new keywords can be created in a folder
+const NEW_KEYWORDS string = "\\*"
+
+// Characters, which aren't allowed in keywords (atom-specials of RFC 3501 without controls)
+const ATOM_SPECIALS string = "(){ %*\"\\]"
+
+// The keywords of a folder
+type FolderKeywords struct {
+	// All keywords, that are defined in the folder (sorted)
+	Keywords []string
+	// Whether new keywords can be stored permanently in the folder
+	CanCreate bool
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////
+///									Public Keyword Methods										 ///
+////////////////////////////////////////////////////////////////////////////////////////////////////
+
+/**
+ * Loads the keywords, which are defined in the given folder (FLAGS), e.g., to offer the labels
+ * already used by other clients.
+ */
+func (mc *MailCon) LoadKeywords(folder string) (*FolderKeywords, error) {
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+	if err := mc.selectFolder(folder, true); err != nil {
+		return nil, err
+	}
+	var keywords *FolderKeywords = &FolderKeywords{Keywords: []string{}, CanCreate: true}
+	if nil == mc.client.Mailbox {
+		return keywords, nil
+	}
+	for flag, set := range mc.client.Mailbox.Flags {
+		if set && !strings.HasPrefix(flag, "\\") {
+			keywords.Keywords = append(keywords.Keywords, flag)
+		}
+	}
+	sort.Strings(keywords.Keywords)
+	// No PERMANENTFLAGS means, that all flags are permanent (RFC 3501)
+	if len(mc.client.Mailbox.PermFlags) > 0 {
+		keywords.CanCreate = mc.client.Mailbox.PermFlags[NEW_KEYWORDS]
+	}
+	return keywords, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////
+///									Private Keyword Methods										 ///
+////////////////////////////////////////////////////////////////////////////////////////////////////
+
+/**
+ * Checks whether all keywords of the given flags are valid and can be stored permanently in the
+ * selected folder (PERMANENTFLAGS of the SELECT response).
+ * ATTENTION: DOES NOT LOCK THE IMAP CONNECTION! => Has to be wrapped into a mutex lock method
+ */
+func (mc *MailCon) checkKeywords_internal(f *Flags) error {
+	for _, keyword := range f.Keywords {
+		if !isValidKeyword(keyword) {
+			return fmt.Errorf("'%s' is not a valid keyword", keyword)
+		}
+		if nil == mc.client.Mailbox || 0 == len(mc.client.Mailbox.PermFlags) {
+			continue
+		}
+		if !mc.client.Mailbox.PermFlags[NEW_KEYWORDS] && !mc.client.Mailbox.PermFlags[keyword] {
+			return fmt.Errorf("Keyword '%s' can't be stored permanently in folder '%s'", keyword,
+				mc.selectedFolder)
+		}
+	}
+	return nil
+}
+
+/**
+ * @return Whether the given keyword is an IMAP atom, which isn't a system flag (RFC 3501), e.g.,
+ *		   "$Junk" -> true | "\Seen" -> false | "To do" -> false
+ */
+func isValidKeyword(keyword string) bool {
+	if 0 == len(keyword) {
+		return false
+	}
+	for _, r := range keyword {
+		if r <= 0x1f || r >= 0x7f || strings.ContainsRune(ATOM_SPECIALS, r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,37 @@
+package mail
+
+import (
+	"github.com/mxk/go-imap/imap"
+	"reflect"
+	"testing"
+)
+
+func TestReadKeywords(t *testing.T) {
+	f := readFlags(&imap.MessageInfo{Flags: imap.FlagSet{"\\Seen": true, "$label1": true,
+		"$Forwarded": true, "\\Recent": true, "$Junk": false}})
+	if !f.Seen || !f.Recent || !reflect.DeepEqual(f.Keywords, []string{"$Forwarded", "$label1"}) {
+		t.Errorf("Keywords haven't been read correctly: %v", f)
+	}
+}
+
+func TestSerializeKeywords(t *testing.T) {
+	fields := SerializeFlags(&Flags{Seen: true, Keywords: []string{"$label1", "To do", ""}})
+	if !reflect.DeepEqual(fields, []imap.Field{"\\Seen", "$label1"}) {
+		t.Errorf("Expected only valid keywords to be serialized, but got: %v", fields)
+	}
+	criteria := flagCriteria(&Flags{Keywords: []string{JUNK_KEYWORD}}, true)
+	if !reflect.DeepEqual(criteria, []imap.Field{"UNKEYWORD", JUNK_KEYWORD}) {
+		t.Errorf("Unexpected search criteria for keywords: %v", criteria)
+	}
+}
+
+func TestIsValidKeyword(t *testing.T) {
+	for keyword, valid := range map[string]bool{
+		"$Junk": true, "$label1": true, "Work": true, "\\Seen": false, "To do": false,
+		"a]b": false, "(x)": false, "": false, "Ärger": false,
+	} {
+		if isValidKeyword(keyword) != valid {
+			t.Errorf("Expected keyword '%s' to be valid: %t", keyword, valid)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package mail
+
+import (
+	"fmt"
+	"github.com/mxk/go-imap/imap"
+	"sort"
+)
+
+// One page of the mails of a folder, whereas the newest mails are on the first page
+type MailPage struct {
+	// The mails of this page (newest first)
+	Mails []Mail
+	// The total number of mails in the folder
+	Total uint32
+	// The number of (newer) mails that precede this page
+	Offset int
+	// The maximum number of mails on this page
+	Limit int
+}
+
+// Used to sort UIDs in ascending order
+type UIDSlice []uint32
+
+func (p UIDSlice) Len() int           { return len(p) }
+func (p UIDSlice) Less(i, j int) bool { return p[i] < p[j] }
+func (p UIDSlice) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+
+////////////////////////////////////////////////////////////////////////////////////////////////////
+///									Public Page Methods											 ///
+////////////////////////////////////////////////////////////////////////////////////////////////////
+
+/**
+ * Loads one page of mails from the given folder, whereas the mails are ordered by their arrival
+ * (sequence number), newest first. E.g., offset=0, limit=50 loads the 50 newest mails.
+ * @param folder The folder to retrieve mails from ("/" = root)
+ * @param offset The number of newest mails to skip
+ * @param limit The maximum number of mails to load (> 0)
+ * @param withContent	True - Also loads the content of all mails
+ *						False - Only loads the headers of all mails
+ */
+func (mc *MailCon) LoadMailPage(folder string, offset, limit int, withContent bool) (MailPage,
+	error) {
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+	var page MailPage = MailPage{Mails: []Mail{}, Offset: offset, Limit: limit}
+	if offset < 0 || limit < 1 {
+		return page, fmt.Errorf("Invalid page (offset: %d, limit: %d)", offset, limit)
+	}
+	// 1) Select the folder to retrieve the total number of mails
+	if err := mc.selectFolder(folder, true); err != nil {
+		return page, err
+	}
+	if nil != mc.client.Mailbox {
+		page.Total = mc.client.Mailbox.Messages
+	}
+	// 2) Compute the sequence range of the page and load its mails
+	from, to, ok := pageSeqRange(page.Total, offset, limit)
+	if !ok {
+		return page, nil
+	}
+	set, _ := imap.NewSeqSet(fmt.Sprintf("%d:%d", from, to))
+	mails, err := mc.loadMails(set, folder, withContent, mc.client.Fetch)
+	if err != nil {
+		return page, err
+	}
+	sort.Sort(MailSlice(mails))
+	page.Mails = mails
+	return page, nil
+}
+
+/**
+ * Loads the next 'limit' mails of the given folder, whose UID is lower than 'beforeUID' (newest
+ * first). In contrast to LoadMailPage, the pages don't shift if new mails arrive, which makes this
+ * method suitable to lazy-load mails while scrolling.
+ * @param beforeUID Only mails with a lower UID are loaded (0 = start with the newest mail)
+ * @param limit The maximum number of mails to load (> 0)
+ */
+func (mc *MailCon) LoadMailPageBeforeUID(folder string, beforeUID uint32, limit int,
+	withContent bool) (MailPage, error) {
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+	var (
+		page MailPage = MailPage{Mails: []Mail{}, Limit: limit}
+		cmd  *imap.Command
+		uids []uint32 = []uint32{}
+		err  error
+	)
+	if limit < 1 {
+		return page, fmt.Errorf("Invalid page limit: %d", limit)
+	}
+	// 1) Retrieve the UIDs of all mails that are older than the given UID
+	if err = mc.selectFolder(folder, true); err != nil {
+		return page, err
+	}
+	if nil != mc.client.Mailbox {
+		page.Total = mc.client.Mailbox.Messages
+	}
+	if beforeUID == 1 || page.Total == 0 {
+		return page, nil
+	}
+	var uidRange string = "1:*"
+	if beforeUID > 1 {
+		uidRange = fmt.Sprintf("1:%d", beforeUID-1)
+	}
+	if cmd, err = mc.waitFor(mc.client.UIDSearch("UID", uidRange)); err != nil {
+		return page, err
+	}
+	for _, resp := range cmd.Data {
+		uids = append(uids, resp.SearchResults()...)
+	}
+	// 2) Load the mails of the 'limit' highest UIDs ("1:n" also contains the highest UID, if that
+	//	  one is > n => filter it)
+	var older []uint32 = []uint32{}
+	for _, uid := range uids {
+		if beforeUID == 0 || uid < beforeUID {
+			older = append(older, uid)
+		}
+	}
+	page.Offset = int(page.Total) - len(older)
+	if 0 == len(older) {
+		return page, nil
+	}
+	sort.Sort(UIDSlice(older))
+	if len(older) > limit {
+		older = older[len(older)-limit:]
+	}
+	set := new(imap.SeqSet)
+	set.AddNum(older...)
+	mails, err := mc.loadMails(set, folder, withContent, mc.client.UIDFetch)
+	if err != nil {
+		return page, err
+	}
+	sort.Sort(MailSlice(mails))
+	page.Mails = mails
+	return page, nil
+}
+
+/**
+ * Computes the sequence range of a page, whereas the newest mail has the highest sequence number.
+ * E.g., total=100, offset=10, limit=20 -> 71:90
+ * @return The first and last sequence number of the page and false, if the page is empty.
+ */
+func pageSeqRange(total uint32, offset, limit int) (from, to uint32, ok bool) {
+	if offset < 0 || limit < 1 || uint64(offset) >= uint64(total) {
+		return 0, 0, false
+	}
+	to = total - uint32(offset)
+	if uint64(limit) >= uint64(to) {
+		return 1, to, true
+	}
+	return to - uint32(limit) + 1, to, true
+}
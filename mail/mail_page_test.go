@@ -0,0 +1,28 @@
+package mail
+
+import (
+	"testing"
+)
+
+func TestPageSeqRange(t *testing.T) {
+	for _, test := range []struct {
+		total         uint32
+		offset, limit int
+		from, to      uint32
+		ok            bool
+	}{
+		{100, 0, 50, 51, 100, true},
+		{100, 10, 20, 71, 90, true},
+		{100, 90, 20, 1, 10, true},
+		{100, 100, 20, 0, 0, false},
+		{0, 0, 20, 0, 0, false},
+		{100, 0, 0, 0, 0, false},
+	} {
+		from, to, ok := pageSeqRange(test.total, test.offset, test.limit)
+		if from != test.from || to != test.to || ok != test.ok {
+			t.Errorf("Expected range (%d:%d, %t) for page (total: %d, offset: %d, limit: %d), "+
+				"but got (%d:%d, %t)", test.from, test.to, test.ok, test.total, test.offset,
+				test.limit, from, to, ok)
+		}
+	}
+}
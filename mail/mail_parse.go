@@ -7,18 +7,20 @@ import (
 	"fmt"
 	"github.com/mxk/go-imap/imap"
 	"io"
-	"io/ioutil"
-	"math"
 	"mime"
-	"mime/multipart"
-	"mime/quotedprintable"
 	"net/textproto"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 )
 
-func parseHeader(mi *imap.MessageInfo) (*Header, error) {
+/**
+ * @param spamProviders The spam filters used to classify the mail (nil = default providers)
+ * @param authServIDs The servers, whose authentication results are trusted (see parseAuthenticity)
+ */
+func parseHeader(mi *imap.MessageInfo, spamProviders []SpamProvider,
+	authServIDs []string) (*Header, error) {
 	// 1) If no MessageInfo was passed => return and error
 	if nil == mi {
 		return nil,
@@ -34,12 +36,20 @@ func parseHeader(mi *imap.MessageInfo) (*Header, error) {
 		return nil, errors.New("Couldn't parse Mail Header, because no header was provided " +
 			"in the given MessageInfo object")
 	}
-	if curHeader, err = parseHeaderStr(imap.AsString(mailHeader)); err == nil {
+	if curHeader, err = parseRawHeader(imap.AsString(mailHeader), spamProviders,
+		authServIDs); err == nil {
 		curHeader.Size = mi.Size
 	}
 	return curHeader, err
 }
 
+/**
+ * Parses the given header and only trusts its topmost authentication results (see parseRawHeader).
+ */
+func parseHeaderStr(header string, spamProviders ...SpamProvider) (*Header, error) {
+	return parseRawHeader(header, spamProviders, nil)
+}
+
 /**
 The Email Header is expected to follow this spec:
 	Mail Header: Return-Path: <root@localhost.localdomain>
@@ -55,7 +65,8 @@ The Email Header is expected to follow this spec:
 which translates to a more generic form of:
 	Key : Blank Value \newline
 */
-func parseHeaderStr(header string) (*Header, error) {
+func parseRawHeader(header string, spamProviders []SpamProvider,
+	authServIDs []string) (*Header, error) {
 	if 0 == len(header) {
 		return nil, errors.New("Header string is empty")
 	}
@@ -70,26 +81,69 @@ func parseHeaderStr(header string) (*Header, error) {
 	//		for key, val := range mHeader {
 	//			fmt.Printf("&&&& %s -> %s\n", key, val)
 	//		}
-	return parseMainHeaderContent(mHeader)
+	return parseMainHeaderContent(mHeader, spamProviders, authServIDs)
 }
 
-func parseMainHeaderContent(headerContentMap textproto.MIMEHeader) (h *Header, err error) {
+func parseMainHeaderContent(headerContentMap textproto.MIMEHeader,
+	spamProviders []SpamProvider, authServIDs []string) (h *Header, err error) {
 	if nil == headerContentMap || 0 == len(headerContentMap) {
 		return nil, errors.New("Header doesn't contain entries")
 	}
 	// Todo: Several of the below used Header members could be missing in the Header string
 	var mHeader PMIMEHeader = parseMIMEHeader(headerContentMap)
 	h = &Header{
-		MimeHeader:    mHeader,
-		Subject:       parseAndDecodeHeader(headerContentMap, "Subject", mHeader),
-		Date:          parseIMAPHeaderDate(headerContentMap),
-		Sender:        parseAndDecodeHeader(headerContentMap, "From", mHeader),
-		Receiver:      parseAndDecodeHeader(headerContentMap, "To", mHeader),
-		SpamIndicator: parseSpamIndicator(headerContentMap),
+		MimeHeader:   mHeader,
+		Subject:      parseAndDecodeHeader(headerContentMap, "Subject", mHeader),
+		Date:         parseIMAPHeaderDate(headerContentMap),
+		Sender:       parseAndDecodeHeader(headerContentMap, "From", mHeader),
+		Receiver:     parseAndDecodeHeader(headerContentMap, "To", mHeader),
+		From:         parseAddressHeader(headerContentMap, "From"),
+		To:           parseAddressHeader(headerContentMap, "To"),
+		Cc:           parseAddressHeader(headerContentMap, "Cc"),
+		Bcc:          parseAddressHeader(headerContentMap, "Bcc"),
+		ReplyTo:      parseAddressHeader(headerContentMap, "Reply-To"),
+		MessageID:    firstMessageID(parseMessageIDs(headerContentMap, "Message-Id")),
+		InReplyTo:    firstMessageID(parseMessageIDs(headerContentMap, "In-Reply-To")),
+		References:   parseMessageIDs(headerContentMap, "References"),
+		Authenticity: parseAuthenticity(headerContentMap, authServIDs),
 	}
+	h.SpamIndicator, h.SpamProvider = parseSpamIndicator(headerContentMap, spamProviders)
 	return h, nil
 }
 
+/**
+ * Extracts all message IDs of the given header field, e.g.:
+ *	"<1@domain.de> (comment) <2@domain.de>" -> ["<1@domain.de>", "<2@domain.de>"]
+ * @return nil, if the header field is missing or doesn't contain any message ID
+ */
+func parseMessageIDs(rawMimeHeader textproto.MIMEHeader, target string) []string {
+	var ids []string
+	for _, value := range rawMimeHeader[target] {
+		for {
+			start := strings.Index(value, "<")
+			if start < 0 {
+				break
+			}
+			end := strings.Index(value[start:], ">")
+			if end < 0 {
+				break
+			}
+			if id := strings.Join(strings.Fields(value[start:start+end+1]), ""); len(id) > 2 {
+				ids = append(ids, id)
+			}
+			value = value[start+end+1:]
+		}
+	}
+	return ids
+}
+
+func firstMessageID(ids []string) string {
+	if 0 == len(ids) {
+		return ""
+	}
+	return ids[0]
+}
+
 /**
  * This method takes a MIMEHeader map and converts it into a modularized version.
  */
@@ -98,6 +152,7 @@ func parseMIMEHeader(mimeHeader textproto.MIMEHeader) PMIMEHeader {
 		params    map[string]string //e.g., "[multipart/mixed; boundary="----=_Part_414413_206767080.1441196149087"]"
 		mediatype string            //e.g., "multipart/mixed"
 		boundary  string            //e.g., "----=_Part_414413_206767080.1441196149087"
+		charset   string            //e.g., "ISO-8859-1"
 		// Special case "quoted-printable": The go multipart code, hides this field by default and
 		// simply directly decodes the body content accordingly -> make it a default here
 		encoding       string = "quoted-printable" //e.g., "quoted-printable", "base64"
@@ -108,6 +163,7 @@ func parseMIMEHeader(mimeHeader textproto.MIMEHeader) PMIMEHeader {
 	if contentArrayStr, ok := mimeHeader["Content-Type"]; ok {
 		if mediatype, params, err = mime.ParseMediaType(contentArrayStr[0]); err == nil {
 			boundary = params["boundary"]
+			charset = params["charset"]
 		} else {
 			fmt.Printf("[watney] WARNING: failed to parse the media type of mail: %s\n",
 				err.Error())
@@ -132,6 +188,7 @@ func parseMIMEHeader(mimeHeader textproto.MIMEHeader) PMIMEHeader {
 		ContentType:       mediatype,
 		Encoding:          encoding,
 		MultipartBoundary: boundary,
+		Charset:           charset,
 	}
 }
 
@@ -143,7 +200,7 @@ func parseAndDecodeHeader(rawMimeHeader textproto.MIMEHeader, target string,
 		var (
 			encodedValue string = strings.TrimPrefix(targetArray[0], " ")
 			decoded      string
-			dec          *mime.WordDecoder = new(mime.WordDecoder)
+			dec          *mime.WordDecoder = newWordDecoder()
 			err          error
 		)
 		if decoded, err = dec.DecodeHeader(encodedValue); err != nil {
@@ -191,134 +248,81 @@ func parseIMAPHeaderDate(rawMimeHeader textproto.MIMEHeader) time.Time {
 /**
  *
  */
-func parseContent(mi *imap.MessageInfo, mimeHeader PMIMEHeader) (Content, error) {
+/**
+ * Parses the content of the given mail.
+ * @return The content of all parts, that are shown as mail body (Content-Type -> Part) and the
+ *		   ordered part tree of the mail (including all attachments)
+ */
+func parseContent(mi *imap.MessageInfo, mimeHeader PMIMEHeader) (Content, *MailPart, error) {
 	// 1) If no content is given, error and return
 	if nil == mi {
-		return nil, errors.New("[watney] ERROR: Couldn't parse mail content due to missing content.")
+		return nil, nil,
+			errors.New("[watney] ERROR: Couldn't parse mail content due to missing content.")
 	}
 	var (
 		content string  = imap.AsString(mi.Attrs["RFC822.TEXT"])
 		parts   Content = make(Content, 1)
+		root    *MailPart
 	)
 	// 2) Simple Case: We have no MIME protocol, simply assume the content is plain text
 	if 0 == mimeHeader.MimeVersion {
 		parts["text/plain"] = ContentPart{
 			Encoding: "quoted-printable",
-			Charset:  "UTF-8",
+			Charset:  CHARSET_ASCII,
 			Body:     content,
 		}
-		return parts, nil
+		root = &MailPart{Section: "1", ContentType: "text/plain", Encoding: "quoted-printable",
+			Size: len(content), body: content}
+		return parts, root, nil
 	}
 	// 3) Otherwise, we have to check the Content-Type: If its NOT a multipart, just add it as is
 	if !strings.Contains(mimeHeader.ContentType, "multipart") {
 		parts[mimeHeader.ContentType] = ContentPart{
 			Encoding: mimeHeader.Encoding,
-			Charset:  "UTF-8",
+			Charset:  mimeHeader.Charset,
 			Body:     content,
 		}
-		return parts, nil
+		root = &MailPart{Section: "1", ContentType: mimeHeader.ContentType,
+			Charset: mimeHeader.Charset, Encoding: mimeHeader.Encoding, Size: len(content),
+			body: content}
+		return parts, root, nil
 	}
 	// 4) Otherwise, in case we have a multipart Content-Type, parse all parts
-	return parseMultipartContent(content, mimeHeader.MultipartBoundary)
-}
-
-func parseMultipartContent(content, boundary string) (Content, error) {
-	var (
-		reader *multipart.Reader = multipart.NewReader(strings.NewReader(content),
-			boundary)
-		part       *multipart.Part
-		mailParts  Content = make(Content, 1)
-		partHeader PMIMEHeader
-		err        error
-	)
-	for {
-		if part, err = reader.NextPart(); err == io.EOF {
-			// 1) EOF error means, we're finished reading the multiparts
-			break
-		} else if err != nil {
-			// 2) other errors are real => print a warning for that part and continue with the next
-			fmt.Printf("[watney] WARNING: Couldn't parse multipart 'Part' Header & Content: %s\n",
-				err.Error())
-			continue
-		}
-		// 3) Try to read the content of this multipart body ...
-		if readBytes, err := ioutil.ReadAll(part); err != nil {
-			fmt.Printf("[watney] WARNING: Couldn't read multipart body content: %s\n", err.Error())
-			continue
-		} else {
-			// 4) The body of this part has been successfully parsed => extract content
-			if partHeader = parseMIMEHeader(part.Header); len(partHeader.ContentType) > 0 {
-				// 5) We got a Content-Type, check if that one is multipart again
-				if strings.Contains(partHeader.ContentType, "multipart") {
-					// 5a) It is multipart => recursively add its parts
-					if innerParts, err := parseMultipartContent(string(readBytes),
-						partHeader.MultipartBoundary); err != nil {
-						fmt.Printf("[watney] WARNING: Couldn't parse inner multipart body: %s\n",
-							err.Error())
-						continue
-					} else {
-						for key, value := range innerParts {
-							mailParts[key] = value
-						}
-					}
-				} else {
-					// 5b) We have a content type other than multipart, just add it
-					mailParts[partHeader.ContentType] = ContentPart{
-						Encoding: partHeader.Encoding,
-						Charset:  "UTF-8",
-						Body:     string(readBytes),
-					}
-				}
-			} else {
-				// 4b) This part has no MIME information -> assume text/plain
-				// ATTENTION: We're overwriting previously parsed text/plain parts
-				mailParts["text/plain"] = ContentPart{
-					Encoding: "quoted-printable",
-					Charset:  "UTF-8",
-					Body:     string(readBytes),
-				}
-			}
-		}
+	root = &MailPart{ContentType: mimeHeader.ContentType, Size: len(content)}
+	children, err := parseMultipartParts(content, mimeHeader.MultipartBoundary, "")
+	if err != nil {
+		return nil, nil, err
 	}
-	return mailParts, nil
+	root.Children = children
+	collectContent(children, parts)
+	return parts, root, nil
 }
 
+/**
+ * Decodes the transfer encoding (base64, quoted-printable) of all given parts and converts their
+ * text from the charset of the part to UTF-8.
+ */
 func decodeContent(content Content) {
 	for contentType, curPart := range content {
-		switch curPart.Encoding {
-		case "quoted-printable":
-			{
-				var buf bytes.Buffer
-				_, err := io.Copy(&buf, quotedprintable.NewReader(strings.NewReader(curPart.Body)))
-				if err != nil {
-					fmt.Printf("[watney] ERROR while trying to decode content of type " +
-						"'quoted-printable'\n")
-					continue
-				}
-				content[contentType] = ContentPart{
-					Encoding: curPart.Encoding,
-					Charset:  curPart.Charset,
-					Body:     buf.String(),
-				}
-			}
+		body, err := decodeBody(curPart.Encoding, []byte(curPart.Body))
+		if err != nil {
+			fmt.Printf("[watney] ERROR while trying to decode content of type '%s': %s\n",
+				curPart.Encoding, err.Error())
+			body = []byte(curPart.Body)
+		}
+		text, err := toUTF8(curPart.Charset, body)
+		if err != nil {
+			fmt.Printf("[watney] WARNING: Couldn't convert content '%s' to UTF-8: %s\n",
+				contentType, err.Error())
+		}
+		content[contentType] = ContentPart{
+			Encoding: curPart.Encoding,
+			Charset:  "UTF-8",
+			Body:     text,
 		}
 	}
 }
 
-func parseSpamIndicator(headerContentMap textproto.MIMEHeader) int {
-	// 0 means: NO spam
-	var gmxSpamValue, watneySpamValue int = 0, 0
-	if spamIndicator, ok := headerContentMap["X-Gmx-Antispam"]; ok {
-		gmxSpamValue = parseGMXSpamIndicator(spamIndicator)
-	}
-	if _, ok := headerContentMap["X-Watney-Antispam"]; ok {
-		// TODO: Not yet implemented
-	}
-	// By default, a mail that is not tagged with any spam indicating header information is
-	// not a SPAM mail
-	return int(math.Max(float64(gmxSpamValue), float64(watneySpamValue)))
-}
-
 /**
  * expected Header content:
  * [0 (Mail was not recognized as spam); Detail=V3;]
@@ -372,6 +376,27 @@ func SerializeHeader(h *Header) string {
 		// TODO: We need to retain the information about the name of the SPAM field
 		fmt.Sprintf("%s: %d", "X-GMX-Antispam", h.SpamIndicator)},
 		"\r\n"))
+	// 3) Further receivers are only added, if given
+	for _, field := range []struct {
+		name      string
+		addresses []Address
+	}{{"Cc", h.Cc}, {"Bcc", h.Bcc}, {"Reply-To", h.ReplyTo}} {
+		if len(field.addresses) > 0 {
+			header = fmt.Sprintf("%s\r\n%s: %s", header, field.name,
+				formatAddresses(field.addresses))
+		}
+	}
+	// 4) Message IDs are only known for received (or already sent) mails
+	if len(h.MessageID) > 0 {
+		header = fmt.Sprintf("%s\r\n%s: %s", header, "Message-ID", h.MessageID)
+	}
+	if len(h.InReplyTo) > 0 {
+		header = fmt.Sprintf("%s\r\n%s: %s", header, "In-Reply-To", h.InReplyTo)
+	}
+	if len(h.References) > 0 {
+		header = fmt.Sprintf("%s\r\n%s: %s", header, "References",
+			strings.Join(h.References, "\r\n\t"))
+	}
 	return header
 }
 
@@ -384,6 +409,13 @@ func readFlags(mi *imap.MessageInfo) (f *Flags) {
 		Draft:    mi.Flags["\\Draft"],
 		Recent:   mi.Flags["\\Recent"],
 	}
+	// All other flags are keywords (system flags always start with a backslash)
+	for flag, set := range mi.Flags {
+		if set && !strings.HasPrefix(flag, "\\") {
+			f.Keywords = append(f.Keywords, flag)
+		}
+	}
+	sort.Strings(f.Keywords)
 	return f
 }
 
@@ -407,5 +439,10 @@ func SerializeFlags(flags *Flags) imap.Field {
 	if flags.Recent {
 		fieldFlags = append(fieldFlags, "\\Recent")
 	}
+	for _, keyword := range flags.Keywords {
+		if isValidKeyword(keyword) {
+			fieldFlags = append(fieldFlags, keyword)
+		}
+	}
 	return fieldFlags
 }
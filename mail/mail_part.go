@@ -0,0 +1,321 @@
+package mail
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/mxk/go-imap/imap"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"regexp"
+	"strings"
+)
+
+// The dispositions of a MIME part (RFC 2183)
+const (
+	DISPOSITION_INLINE     string = "inline"     // The part is shown as part of the mail body
+	DISPOSITION_ATTACHMENT string = "attachment" // The part is shown as a downloadable file
+)
+
+// One node of the MIME part tree of a mail. Leaf nodes hold the actual content, e.g., the text of
+// the mail or an attachment, multipart nodes group their children.
+type MailPart struct {
+	// The IMAP section specifier of this part, e.g., "1", "2.1" ("" = multipart root of the mail)
+	Section string
+	// The media-type of this part, e.g., text/plain, application/pdf, multipart/mixed
+	ContentType string
+	// The charset of the body of this part (only given for text parts)
+	Charset string
+	// The transfer encoding of the body, e.g., quoted-printable, base64
+	Encoding string
+	// Whether the part is shown inline or as attachment (empty, if not given by the mail)
+	Disposition string
+	// The file name of the part (only given for attachments)
+	Filename string
+	// The Content-ID of the part without angle brackets (used to reference inline images)
+	ContentID string
+	// The size of the (encoded) body of this part in bytes
+	Size int
+	// The sub-parts of a multipart in their original order
+	Children []*MailPart
+	// The (encoded) body of this part
+	body string
+}
+
+// Valid IMAP section specifiers of a body part, e.g., "1", "2.1.3"
+var sectionRegex *regexp.Regexp = regexp.MustCompile(`^[1-9][0-9]*(\.[1-9][0-9]*)*$`)
+
+/**
+ * @return Whether this part is an attachment, i.e., it either is marked as such or has a file name
+ */
+func (p *MailPart) IsAttachment() bool {
+	return p.Disposition == DISPOSITION_ATTACHMENT ||
+		(len(p.Filename) > 0 && p.Disposition != DISPOSITION_INLINE)
+}
+
+/**
+ * @return The part with the given section in the tree of this part or nil, if there is no such
+ *		   part
+ */
+func (p *MailPart) Find(section string) *MailPart {
+	if nil == p {
+		return nil
+	}
+	if p.Section == section {
+		return p
+	}
+	for _, child := range p.Children {
+		if found := child.Find(section); nil != found {
+			return found
+		}
+	}
+	return nil
+}
+
+/**
+ * @return All leaf parts of this part's tree, that are attachments, in their original order
+ */
+func (p *MailPart) Attachments() []*MailPart {
+	var attachments []*MailPart = []*MailPart{}
+	if nil == p {
+		return attachments
+	}
+	if 0 == len(p.Children) && p.IsAttachment() {
+		attachments = append(attachments, p)
+	}
+	for _, child := range p.Children {
+		attachments = append(attachments, child.Attachments()...)
+	}
+	return attachments
+}
+
+/**
+ * @return All attachments of this part's tree with their decoded content, e.g., to forward them
+ */
+func (p *MailPart) decodeAttachments() ([]Attachment, error) {
+	var attachments []Attachment = []Attachment{}
+	for _, part := range p.Attachments() {
+		data, err := decodeBody(part.Encoding, []byte(part.body))
+		if err != nil {
+			return attachments, fmt.Errorf("Attachment '%s' couldn't be decoded: %s",
+				part.Filename, err.Error())
+		}
+		attachments = append(attachments, Attachment{
+			Filename:    part.Filename,
+			ContentType: part.ContentType,
+			Data:        data,
+		})
+	}
+	return attachments, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////
+///									Public Part Methods											 ///
+////////////////////////////////////////////////////////////////////////////////////////////////////
+
+/**
+ * Loads a single body part of a mail (BODY[section]) without loading the rest of the mail, e.g.,
+ * to download an attachment.
+ * @param folder The folder of the mail ("/" = root)
+ * @param uid The UID of the mail
+ * @param section The IMAP section specifier of the part, e.g., "2" or "1.2" (see MailPart)
+ * @return The MIME information of the part and its decoded body
+ */
+func (mc *MailCon) LoadMailPart(folder string, uid uint32, section string) (*MailPart, []byte,
+	error) {
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+	if !sectionRegex.MatchString(section) {
+		return nil, nil, fmt.Errorf("Invalid body section '%s'", section)
+	}
+	if err := mc.selectFolder(folder, true); err != nil {
+		return nil, nil, err
+	}
+	var (
+		cmd    *imap.Command
+		header []byte
+		body   []byte
+		err    error
+	)
+	set, _ := imap.NewSeqSet(fmt.Sprintf("%d", uid))
+	// 1) Fetch the MIME header of the part together with its body (without setting \Seen)
+	if cmd, err = mc.waitFor(mc.client.UIDFetch(set, fmt.Sprintf("BODY.PEEK[%s.MIME]", section),
+		fmt.Sprintf("BODY.PEEK[%s]", section))); err != nil {
+		return nil, nil, err
+	}
+	for _, resp := range cmd.Data {
+		if info := resp.MessageInfo(); nil != info && info.UID == uid {
+			header = imap.AsBytes(info.Attrs[fmt.Sprintf("BODY[%s.MIME]", section)])
+			body = imap.AsBytes(info.Attrs[fmt.Sprintf("BODY[%s]", section)])
+		}
+	}
+	mc.clearData()
+	if nil == body {
+		return nil, nil, fmt.Errorf("Mail %d in folder '%s' has no body part '%s'", uid, folder,
+			section)
+	}
+	// 2) Parse the MIME header of the part and decode its body accordingly
+	var mimeHeader textproto.MIMEHeader
+	if 0 < len(header) {
+		reader := textproto.NewReader(bufio.NewReader(bytes.NewReader(header)))
+		if mimeHeader, err = reader.ReadMIMEHeader(); err != nil && err != io.EOF {
+			return nil, nil, err
+		}
+	}
+	part := newMailPart(mimeHeader, section, string(body))
+	if body, err = decodeBody(part.Encoding, body); err != nil {
+		return nil, nil, err
+	}
+	return part, body, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////
+///									Private Part Methods										 ///
+////////////////////////////////////////////////////////////////////////////////////////////////////
+
+/**
+ * Creates the part for the given MIME header and body.
+ */
+func newMailPart(header textproto.MIMEHeader, section, body string) *MailPart {
+	var (
+		mimeHeader PMIMEHeader = parseMIMEHeader(header)
+		part       *MailPart   = &MailPart{
+			Section:     section,
+			ContentType: mimeHeader.ContentType,
+			Encoding:    mimeHeader.Encoding,
+			ContentID:   strings.Trim(strings.TrimSpace(header.Get("Content-Id")), "<>"),
+			Size:        len(body),
+			body:        body,
+		}
+		dec *mime.WordDecoder = newWordDecoder()
+	)
+	if 0 == len(part.ContentType) {
+		part.ContentType = "text/plain"
+	}
+	if _, params, err := mime.ParseMediaType(header.Get("Content-Type")); err == nil {
+		part.Charset = params["charset"]
+		part.Filename = params["name"]
+	}
+	if disposition, params, err := mime.ParseMediaType(header.Get("Content-Disposition")); err ==
+		nil {
+		part.Disposition = disposition
+		if len(params["filename"]) > 0 {
+			part.Filename = params["filename"]
+		}
+	}
+	// Most mail clients encode non-ASCII file names as encoded words instead of RFC 2231
+	if decoded, err := dec.DecodeHeader(part.Filename); err == nil {
+		part.Filename = decoded
+	}
+	return part
+}
+
+/**
+ * Parses the given multipart body into an ordered list of parts (recursively for nested
+ * multiparts).
+ * @param section The section of the multipart itself ("" = root of the mail)
+ */
+func parseMultipartParts(content, boundary, section string) ([]*MailPart, error) {
+	var (
+		reader *multipart.Reader = multipart.NewReader(strings.NewReader(content), boundary)
+		part   *multipart.Part
+		parts  []*MailPart = []*MailPart{}
+		err    error
+	)
+	for {
+		if part, err = reader.NextPart(); err == io.EOF {
+			// 1) EOF error means, we're finished reading the multiparts
+			break
+		} else if err != nil {
+			// 2) other errors are real => stop here, since the reader can't skip broken parts
+			fmt.Printf("[watney] WARNING: Couldn't parse multipart 'Part' Header & Content: %s\n",
+				err.Error())
+			break
+		}
+		// 3) Try to read the content of this multipart body ...
+		readBytes, err := ioutil.ReadAll(part)
+		if err != nil {
+			fmt.Printf("[watney] WARNING: Couldn't read multipart body content: %s\n", err.Error())
+			continue
+		}
+		// 4) ... and create the part with the next section number
+		var curSection string = fmt.Sprintf("%d", len(parts)+1)
+		if len(section) > 0 {
+			curSection = fmt.Sprintf("%s.%d", section, len(parts)+1)
+		}
+		curPart := newMailPart(textproto.MIMEHeader(part.Header), curSection, string(readBytes))
+		// 5) Nested multiparts contain their parts as children
+		if strings.HasPrefix(curPart.ContentType, "multipart/") {
+			boundary := parseMIMEHeader(textproto.MIMEHeader(part.Header)).MultipartBoundary
+			if curPart.Children, err = parseMultipartParts(curPart.body, boundary,
+				curSection); err != nil {
+				fmt.Printf("[watney] WARNING: Couldn't parse inner multipart body: %s\n",
+					err.Error())
+			}
+			curPart.body = ""
+		}
+		parts = append(parts, curPart)
+	}
+	return parts, nil
+}
+
+/**
+ * Collects the content of all leaf parts of the given parts, that aren't attachments. If several
+ * parts have the same Content-Type, the first one is kept.
+ */
+func collectContent(parts []*MailPart, content Content) {
+	for _, part := range parts {
+		if len(part.Children) > 0 {
+			collectContent(part.Children, content)
+			continue
+		}
+		// Inline images are no content of their own, they are referenced by the HTML part
+		if _, ok := content[part.ContentType]; ok || part.IsAttachment() || part.IsInlineImage() ||
+			strings.HasPrefix(part.ContentType, "multipart/") {
+			continue
+		}
+		// Text without a charset is US-ASCII (RFC 2045)
+		var charset string = part.Charset
+		if 0 == len(charset) {
+			charset = CHARSET_ASCII
+		}
+		content[part.ContentType] = ContentPart{
+			Encoding: part.Encoding,
+			Charset:  charset,
+			Body:     part.body,
+		}
+	}
+}
+
+/**
+ * Decodes the given body according to the given transfer encoding (base64, quoted-printable).
+ * Bodies with other encodings (7bit, 8bit, binary) are returned as is.
+ */
+func decodeBody(encoding string, body []byte) ([]byte, error) {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		// Base64 bodies are split into lines, which the decoder doesn't accept
+		var cleaned []byte = bytes.Map(func(r rune) rune {
+			if r == '\r' || r == '\n' || r == ' ' || r == '\t' {
+				return -1
+			}
+			return r
+		}, body)
+		decoded := make([]byte, base64.StdEncoding.DecodedLen(len(cleaned)))
+		n, err := base64.StdEncoding.Decode(decoded, cleaned)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Couldn't decode base64 body: %s", err.Error()))
+		}
+		return decoded[:n], nil
+	case "quoted-printable":
+		return ioutil.ReadAll(quotedprintable.NewReader(bytes.NewReader(body)))
+	default:
+		return body, nil
+	}
+}
@@ -0,0 +1,76 @@
+package mail
+
+import (
+	"strings"
+	"testing"
+)
+
+var attachmentMailBody string = strings.Replace(`--outer
+Content-Type: multipart/alternative; boundary="inner"
+
+--inner
+Content-Type: text/plain; charset="UTF-8"
+
+Some test text!
+--inner
+Content-Type: text/html; charset="UTF-8"
+
+<b>Some test text!</b>
+--inner--
+--outer
+Content-Type: application/pdf; name="potatoes.pdf"
+Content-Disposition: attachment; filename="potatoes.pdf"
+Content-Transfer-Encoding: base64
+
+UG90YXRv
+ZXM=
+--outer
+Content-Type: application/pdf
+Content-Disposition: attachment; filename="=?UTF-8?Q?W=C3=A4sser.pdf?="
+Content-Transfer-Encoding: base64
+
+V2F0ZXI=
+--outer
+Content-Type: image/png
+Content-Disposition: inline
+Content-ID: <logo@watney>
+Content-Transfer-Encoding: base64
+
+iVBORw0K
+--outer--
+`, "\n", "\r\n", -1)
+
+func TestParseMultipartParts(t *testing.T) {
+	parts, err := parseMultipartParts(attachmentMailBody, "outer", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parts) != 4 || len(parts[0].Children) != 2 {
+		t.Fatalf("Expected 4 parts with 2 alternatives, but got %d parts", len(parts))
+	}
+	root := &MailPart{ContentType: "multipart/mixed", Children: parts}
+	if html := root.Find("1.2"); nil == html || html.ContentType != "text/html" ||
+		html.Charset != "UTF-8" {
+		t.Errorf("Expected text/html part with section 1.2, but got %v", html)
+	}
+	attachments := root.Attachments()
+	if len(attachments) != 2 || attachments[0].Filename != "potatoes.pdf" ||
+		attachments[1].Filename != "Wässer.pdf" || attachments[1].Section != "3" {
+		t.Fatalf("Attachments haven't been parsed correctly: %v", attachments)
+	}
+	if body, err := decodeBody(attachments[0].Encoding,
+		[]byte(attachments[0].body)); err != nil || string(body) != "Potatoes" {
+		t.Errorf("Expected decoded body 'Potatoes', but got '%s' (%v)", body, err)
+	}
+	if image := root.Find("4"); image.ContentID != "logo@watney" || image.IsAttachment() {
+		t.Errorf("Expected inline image with Content-ID, but got %v", image)
+	}
+	// Attachments and inline images must not overwrite the text content of the mail
+	content := make(Content)
+	collectContent(parts, content)
+	_, hasAttachment := content["application/pdf"]
+	if len(content) != 2 || hasAttachment ||
+		strings.TrimSpace(content["text/plain"].Body) != "Some test text!" {
+		t.Errorf("Unexpected mail content: %v", content)
+	}
+}
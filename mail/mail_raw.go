@@ -0,0 +1,92 @@
+package mail
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"github.com/mxk/go-imap/imap"
+	"net/textproto"
+	"strings"
+	"unicode"
+)
+
+// The maximum length of a file name created by EMLFilename (without the extension)
+const MAX_EML_FILENAME_LENGTH int = 80
+
+////////////////////////////////////////////////////////////////////////////////////////////////////
+///									Public Raw Methods											 ///
+////////////////////////////////////////////////////////////////////////////////////////////////////
+
+/**
+ * Loads the complete source of a mail as stored on the server (header and body with all MIME
+ * parts), e.g., to debug delivery problems or to download the mail as .eml file. The \Seen flag
+ * of the mail isn't changed.
+ * @param folder The folder of the mail ("/" = root)
+ * @param uid The UID of the mail
+ * @return The raw message (RFC 5322)
+ */
+func (mc *MailCon) LoadRawMail(folder string, uid uint32) ([]byte, error) {
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+	if uid < 1 {
+		return nil, fmt.Errorf("Couldn't retrieve mail, because mail UID (%d) needs to be "+
+			"greater than 0", uid)
+	}
+	if err := mc.selectFolder(folder, true); err != nil {
+		return nil, err
+	}
+	var (
+		cmd *imap.Command
+		raw []byte
+		err error
+	)
+	set, _ := imap.NewSeqSet(fmt.Sprintf("%d", uid))
+	if cmd, err = mc.waitFor(mc.client.UIDFetch(set, "BODY.PEEK[]")); err != nil {
+		return nil, err
+	}
+	for _, resp := range cmd.Data {
+		if info := resp.MessageInfo(); nil != info && info.UID == uid {
+			raw = imap.AsBytes(info.Attrs["BODY[]"])
+		}
+	}
+	mc.clearData()
+	if nil == raw {
+		return nil, fmt.Errorf("No mail found for the given ID: %d", uid)
+	}
+	return raw, nil
+}
+
+/**
+ * @return A file name for the given raw mail, which is derived from its subject, e.g.,
+ *		   "Re: Mars/Earth?" -> "Re_ Mars_Earth_.eml" (mails without subject are named after
+ *		   their UID, e.g., "mail-42.eml")
+ */
+func EMLFilename(raw []byte, uid uint32) string {
+	var (
+		reader  *textproto.Reader = textproto.NewReader(bufio.NewReader(bytes.NewReader(raw)))
+		subject string
+	)
+	// The header may be incomplete, if the mail is malformed => use what has been read
+	if header, _ := reader.ReadMIMEHeader(); nil != header {
+		subject = header.Get("Subject")
+		if decoded, err := newWordDecoder().DecodeHeader(subject); err == nil {
+			subject = decoded
+		}
+	}
+	// 1) Replace all characters, which aren't allowed in file names of common file systems
+	var name []rune
+	for _, r := range strings.TrimSpace(subject) {
+		if len(name) >= MAX_EML_FILENAME_LENGTH {
+			break
+		}
+		if unicode.IsControl(r) || strings.ContainsRune(`/\:*?"<>|`, r) {
+			r = '_'
+		}
+		name = append(name, r)
+	}
+	// 2) Names consisting of dots only would refer to directories
+	if 0 == len(strings.Trim(string(name), ". ")) {
+		return fmt.Sprintf("mail-%d.eml", uid)
+	}
+	return strings.TrimSpace(string(name)) + ".eml"
+}
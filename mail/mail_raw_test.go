@@ -0,0 +1,19 @@
+package mail
+
+import (
+	"testing"
+)
+
+func TestEMLFilename(t *testing.T) {
+	for raw, expected := range map[string]string{
+		"From: mark@mars.com\r\nSubject: Re: Potatoes/Mars?\r\n\r\nBody": "Re_ Potatoes_Mars_.eml",
+		"Subject: =?UTF-8?Q?Gr=C3=BC=C3=9Fe?=\r\n\r\n":                   "Grüße.eml",
+		"Subject: ..\r\n\r\n":                   "mail-42.eml",
+		"From: mark@mars.com\r\n\r\nNo subject": "mail-42.eml",
+		"Subject:  Tabs\tand spaces \r\n\r\n":   "Tabs_and spaces.eml",
+	} {
+		if filename := EMLFilename([]byte(raw), 42); filename != expected {
+			t.Errorf("Expected file name '%s', but got '%s'", expected, filename)
+		}
+	}
+}
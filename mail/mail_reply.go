@@ -0,0 +1,190 @@
+package mail
+
+import (
+	"fmt"
+	"github.com/mxk/go-imap/imap"
+	"strings"
+	"time"
+)
+
+// Types of mails, that refer to an original mail
+const (
+	REPLY     string = "reply"    // Reply to the sender of the original mail
+	REPLY_ALL string = "replyAll" // Reply to the sender and all receivers of the original mail
+	FORWARD   string = "forward"  // Forward the original mail including its attachments
+)
+
+// Keyword set on forwarded mails (there is no system flag for that, see RFC 5788)
+const FORWARDED_FLAG string = "$Forwarded"
+
+// The original mail an outgoing mail refers to, which is flagged once the mail has been sent
+type MailReference struct {
+	// The folder of the original mail ("/" = root)
+	Folder string
+	// The UID of the original mail
+	UID uint32
+	// How the outgoing mail refers to the original mail: REPLY | REPLY_ALL | FORWARD
+	Type string
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////
+///									Public Reply Methods										 ///
+////////////////////////////////////////////////////////////////////////////////////////////////////
+
+/**
+ * Creates a reply, reply-all or forward draft of the given mail, which can be edited and then be
+ * sent with SendMail. Sending the draft sets the \Answered or $Forwarded flag of the original mail.
+ * @param folder The folder of the original mail ("/" = root)
+ * @param uid The UID of the original mail
+ * @param replyType REPLY | REPLY_ALL | FORWARD
+ * @param own The address of the user, which is never added as receiver
+ */
+func (mc *MailCon) ComposeReply(folder string, uid uint32, replyType, own string) (*OutgoingMail,
+	error) {
+	if replyType != REPLY && replyType != REPLY_ALL && replyType != FORWARD {
+		return nil, fmt.Errorf("Unknown reply type '%s'", replyType)
+	}
+	orig, err := mc.LoadMailFromFolderWithUID(folder, uid)
+	if err != nil {
+		return nil, err
+	}
+	if 0 == len(orig.Header.Folder) {
+		orig.Header.Folder = folder
+	}
+	return buildReply(orig, replyType, own)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////
+///									Private Reply Methods										 ///
+////////////////////////////////////////////////////////////////////////////////////////////////////
+
+/**
+ * Sets the \Answered (reply) or $Forwarded (forward) flag of the given original mail.
+ * ATTENTION: DOES NOT LOCK THE IMAP CONNECTION! => Has to be wrapped into a mutex lock method
+ */
+func (mc *MailCon) markOrigin_internal(origin *MailReference) error {
+	var flag imap.Field = "\\Answered"
+	if origin.Type == FORWARD {
+		flag = FORWARDED_FLAG
+	}
+	if err := mc.selectFolder(origin.Folder, false); err != nil {
+		return err
+	}
+	set, _ := imap.NewSeqSet(fmt.Sprintf("%d", origin.UID))
+	_, err := mc.waitFor(mc.client.UIDStore(set, "+FLAGS", []imap.Field{flag}))
+	return err
+}
+
+/**
+ * Creates the draft of a reply to (or forward of) the given mail.
+ */
+func buildReply(orig Mail, replyType, own string) (*OutgoingMail, error) {
+	var (
+		draft *OutgoingMail = &OutgoingMail{
+			From:   own,
+			Origin: &MailReference{Folder: orig.Header.Folder, UID: orig.UID, Type: replyType},
+		}
+		text string
+	)
+	if part, ok := orig.Content["text/plain"]; ok {
+		text = part.Body
+	}
+	// 1) Forwards contain the original mail and all of its attachments
+	if replyType == FORWARD {
+		draft.Subject = prefixSubject("Fwd: ", orig.Header.Subject, "fwd:", "fw:")
+		draft.Text = strings.Join([]string{"", "", "---------- Forwarded message ----------",
+			fmt.Sprintf("From: %s", orig.Header.Sender),
+			fmt.Sprintf("Date: %s", orig.Header.Date.Format(time.RFC1123Z)),
+			fmt.Sprintf("Subject: %s", orig.Header.Subject),
+			fmt.Sprintf("To: %s", orig.Header.Receiver),
+			"", text}, "\n")
+		var err error
+		draft.Attachments, err = orig.Parts.decodeAttachments()
+		return draft, err
+	}
+	// 2) Replies are sent to the Reply-To (or From) addresses and for reply-all also to all
+	//	  receivers of the original mail (without the own address)
+	var (
+		seen map[string]bool = map[string]bool{strings.ToLower(bareAddress(own)): true}
+		to   []Address       = orig.Header.ReplyTo
+	)
+	if 0 == len(to) {
+		to = orig.Header.From
+	}
+	if replyType == REPLY_ALL {
+		to = append(append([]Address{}, to...), orig.Header.To...)
+	}
+	draft.To = uniqueAddresses(to, seen)
+	if 0 == len(draft.To) {
+		// Replying to an own mail (e.g., from the Sent folder) => reply to its receivers instead
+		draft.To = uniqueAddresses(orig.Header.To, seen)
+	}
+	if replyType == REPLY_ALL {
+		draft.Cc = uniqueAddresses(orig.Header.Cc, seen)
+	}
+	draft.Subject = prefixSubject("Re: ", orig.Header.Subject, "re:")
+	draft.Text = fmt.Sprintf("\n\nOn %s, %s wrote:\n%s",
+		orig.Header.Date.Format("Mon, 2 Jan 2006 at 15:04"), orig.Header.Sender, quoteText(text))
+	// 3) Link the reply to the original mail for threading
+	if len(orig.Header.MessageID) > 0 {
+		draft.InReplyTo = orig.Header.MessageID
+		draft.References = append(append([]string{}, orig.Header.References...),
+			orig.Header.MessageID)
+	}
+	return draft, nil
+}
+
+/**
+ * @return The formatted addresses of the given list, which aren't contained in 'seen' yet (all
+ *		   returned addresses are added to 'seen')
+ */
+func uniqueAddresses(addresses []Address, seen map[string]bool) []string {
+	var unique []string = []string{}
+	for _, addr := range addresses {
+		if key := strings.ToLower(addr.Address); !seen[key] {
+			seen[key] = true
+			unique = append(unique, addr.String())
+		}
+	}
+	return unique
+}
+
+/**
+ * @return The bare address of the given address, e.g., "Mark <mark@mars.com>" -> mark@mars.com
+ */
+func bareAddress(address string) string {
+	if addresses, err := ParseAddresses([]string{address}); err == nil && 1 == len(addresses) {
+		return addresses[0].Address
+	}
+	return strings.TrimSpace(address)
+}
+
+/**
+ * Adds the given prefix to the subject, unless it already starts with one of the known prefixes.
+ * E.g.: ("Re: ", "Potatoes", "re:") -> "Re: Potatoes" | ("Re: ", "RE: Potatoes") -> "RE: Potatoes"
+ */
+func prefixSubject(prefix, subject string, known ...string) string {
+	var lower string = strings.ToLower(strings.TrimSpace(subject))
+	for _, knownPrefix := range known {
+		if strings.HasPrefix(lower, knownPrefix) {
+			return strings.TrimSpace(subject)
+		}
+	}
+	return prefix + strings.TrimSpace(subject)
+}
+
+/**
+ * Quotes each line of the given text with '> ', e.g.: "Hi\nMark" -> "> Hi\n> Mark"
+ */
+func quoteText(text string) string {
+	var lines []string = strings.Split(strings.TrimRight(strings.Replace(text, "\r\n", "\n", -1),
+		"\n"), "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, ">") {
+			lines[i] = ">" + line
+		} else {
+			lines[i] = "> " + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
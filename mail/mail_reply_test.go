@@ -0,0 +1,64 @@
+package mail
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func replyTestMail() Mail {
+	return Mail{
+		UID: 42,
+		Header: &Header{
+			Folder:     "/",
+			Subject:    "Potatoes",
+			Sender:     "Mark <mark@mars.com>",
+			Date:       time.Date(2035, 11, 7, 10, 30, 0, 0, time.UTC),
+			From:       []Address{{Name: "Mark", Address: "mark@mars.com"}},
+			To:         []Address{{Address: "me@hermes.com"}, {Address: "rick@hermes.com"}},
+			Cc:         []Address{{Address: "venkat@nasa.gov"}, {Address: "Mark@mars.com"}},
+			MessageID:  "<2@mars>",
+			References: []string{"<1@mars>"},
+		},
+		Content: Content{"text/plain": ContentPart{Body: "Grow them!\r\n> Earlier\r\n"}},
+	}
+}
+
+func TestBuildReply(t *testing.T) {
+	reply, err := buildReply(replyTestMail(), REPLY_ALL, "Me <me@hermes.com>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(reply.To, []string{`"Mark" <mark@mars.com>`, "<rick@hermes.com>"}) ||
+		!reflect.DeepEqual(reply.Cc, []string{"<venkat@nasa.gov>"}) {
+		t.Errorf("Unexpected receivers: To %v, Cc %v", reply.To, reply.Cc)
+	}
+	if reply.Subject != "Re: Potatoes" || reply.InReplyTo != "<2@mars>" ||
+		!reflect.DeepEqual(reply.References, []string{"<1@mars>", "<2@mars>"}) {
+		t.Errorf("Unexpected reply header: %v", reply)
+	}
+	if !strings.HasSuffix(reply.Text, "wrote:\n> Grow them!\n>> Earlier") {
+		t.Errorf("Original text hasn't been quoted correctly: %q", reply.Text)
+	}
+	if reply.Origin.UID != 42 || reply.Origin.Type != REPLY_ALL {
+		t.Errorf("Unexpected origin: %v", reply.Origin)
+	}
+	forward, err := buildReply(replyTestMail(), FORWARD, "me@hermes.com")
+	if err != nil || forward.Subject != "Fwd: Potatoes" || len(forward.To) != 0 ||
+		len(forward.InReplyTo) != 0 {
+		t.Errorf("Unexpected forward: %v (%v)", forward, err)
+	}
+}
+
+func TestPrefixSubject(t *testing.T) {
+	for _, test := range [][]string{
+		{"Re: ", "Potatoes", "Re: Potatoes"},
+		{"Re: ", "RE: Potatoes", "RE: Potatoes"},
+		{"Fwd: ", " Fw: Potatoes", "Fw: Potatoes"},
+	} {
+		if subject := prefixSubject(test[0], test[1], "re:", "fwd:", "fw:"); subject != test[2] {
+			t.Errorf("Expected subject '%s', but got '%s'", test[2], subject)
+		}
+	}
+}
@@ -0,0 +1,552 @@
+package mail
+
+import (
+	"bytes"
+	"html"
+	"net/url"
+	"strings"
+)
+
+// Elements, which are kept by SanitizeHTML: tag name -> allowed attributes (besides global ones)
+var allowedElements map[string][]string = map[string][]string{
+	"a": {"href", "name"}, "abbr": nil, "b": nil, "big": nil, "blockquote": nil, "br": nil,
+	"caption": nil, "center": nil, "cite": nil, "code": nil, "col": {"span"},
+	"colgroup": {"span"}, "dd": nil, "del": nil, "div": nil, "dl": nil, "dt": nil, "em": nil,
+	"font": {"color", "face", "size"}, "h1": nil, "h2": nil, "h3": nil, "h4": nil, "h5": nil,
+	"h6": nil, "hr": {"size", "noshade"}, "i": nil, "img": {"src", "alt", "border", "hspace",
+		"vspace"}, "ins": nil, "kbd": nil, "li": {"type", "value"}, "ol": {"start", "type"},
+	"p": nil, "pre": nil, "q": nil, "s": nil, "small": nil, "span": nil, "strike": nil,
+	"strong": nil, "sub": nil, "sup": nil, "table": {"border", "cellpadding", "cellspacing",
+		"summary"}, "tbody": nil, "td": {"colspan", "rowspan", "nowrap"}, "tfoot": nil,
+	"th": {"colspan", "rowspan", "nowrap", "scope"}, "thead": nil, "tr": nil, "tt": nil,
+	"u": nil, "ul": {"type"},
+}
+
+// Attributes, which are allowed for all elements in allowedElements
+var globalAttributes []string = []string{"align", "bgcolor", "dir", "height", "lang", "style",
+	"title", "valign", "width"}
+
+// Elements, which are removed by SanitizeHTML together with their content
+var droppedElements map[string]bool = map[string]bool{
+	"applet": true, "audio": true, "button": true, "embed": true, "frameset": true, "head": true,
+	"iframe": true, "math": true, "noembed": true, "noframes": true, "noscript": true,
+	"object": true, "script": true, "select": true, "style": true, "svg": true,
+	"template": true, "textarea": true, "title": true, "video": true, "xmp": true,
+}
+
+// Elements, whose content isn't parsed as HTML, but as text up to their end tag
+var rawTextElements map[string]bool = map[string]bool{
+	"iframe": true, "noembed": true, "noframes": true, "noscript": true, "script": true,
+	"style": true, "textarea": true, "title": true, "xmp": true,
+}
+
+// Elements without content and end tag
+var voidElements map[string]bool = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "param": true, "source": true, "track": true,
+	"wbr": true,
+}
+
+// CSS properties, which are kept in style attributes (positioning properties are removed, since
+// they allow a mail to cover the page)
+var allowedCSSProperties map[string]bool = map[string]bool{
+	"background-color": true, "border": true, "border-bottom": true, "border-collapse": true,
+	"border-color": true, "border-left": true, "border-radius": true, "border-right": true,
+	"border-spacing": true, "border-style": true, "border-top": true, "border-width": true,
+	"color": true, "direction": true, "display": true, "font": true, "font-family": true,
+	"font-size": true, "font-style": true, "font-variant": true, "font-weight": true,
+	"height": true, "letter-spacing": true, "line-height": true, "list-style-type": true,
+	"margin": true, "margin-bottom": true, "margin-left": true, "margin-right": true,
+	"margin-top": true, "max-width": true, "min-width": true, "padding": true,
+	"padding-bottom": true, "padding-left": true, "padding-right": true, "padding-top": true,
+	"text-align": true, "text-decoration": true, "text-indent": true, "text-transform": true,
+	"vertical-align": true, "white-space": true, "width": true, "word-spacing": true,
+	"word-wrap": true,
+}
+
+// The kinds of tokens returned by the htmlTokenizer
+const (
+	textToken = iota
+	startTagToken
+	endTagToken
+	// Comments, doctype declarations and processing instructions
+	ignoredToken
+)
+
+// A single attribute of a start tag
+type htmlAttribute struct {
+	// The lower case attribute name
+	Name string
+	// The unescaped attribute value
+	Value string
+}
+
+// A token of a HTML document
+type htmlToken struct {
+	// One of textToken, startTagToken, endTagToken or ignoredToken
+	Kind int
+	// The raw text (textToken) or the lower case tag name (startTagToken, endTagToken)
+	Data string
+	// The attributes of a start tag
+	Attributes []htmlAttribute
+	// Whether the start tag is self-closing, e.g., <br/>
+	SelfClosing bool
+}
+
+// A simple HTML tokenizer, which splits a document into text and tags without building a tree
+type htmlTokenizer struct {
+	// The HTML document
+	input string
+	// The current position within the input
+	pos int
+	// The name of the raw text element, whose content has to be read next (see rawTextElements)
+	rawText string
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////
+///									Public Sanitize Methods										 ///
+////////////////////////////////////////////////////////////////////////////////////////////////////
+
+/**
+ * Removes everything from the given HTML, which shouldn't reach the browser of the user: scripts,
+ * event handlers, forms, frames, style sheets and all elements and attributes not allowed
+ * explicitly. Links are opened in a new window without referrer and unclosed elements are closed.
+ * @param blockRemote True  - Images loaded from remote servers are removed (tracking pixels)
+ *					  False - Remote images are kept
+ * @param inlineURL Maps the Content-ID of an inline image (cid: URL) to the URL the image is
+ *					loaded from ("" = image is removed). If nil, cid: URLs are kept as is.
+ * @return The sanitized HTML and whether remote content has been removed
+ */
+func SanitizeHTML(body string, blockRemote bool,
+	inlineURL func(contentID string) string) (string, bool) {
+	var (
+		buf       bytes.Buffer
+		tokenizer *htmlTokenizer = &htmlTokenizer{input: body}
+		// The currently open elements, which have to be closed at the end
+		open []string
+		// The element (and its nesting depth), whose content is currently being dropped
+		dropping      string
+		dropDepth     int
+		remoteBlocked bool
+	)
+	for token, ok := tokenizer.next(); ok; token, ok = tokenizer.next() {
+		// 1) Skip the content of dropped elements
+		if len(dropping) > 0 {
+			if token.Data == dropping && startTagToken == token.Kind && !token.SelfClosing {
+				dropDepth++
+			} else if token.Data == dropping && endTagToken == token.Kind {
+				if dropDepth--; 0 == dropDepth {
+					dropping = ""
+				}
+			}
+			continue
+		}
+		switch token.Kind {
+		case textToken:
+			buf.WriteString(token.Data)
+		case startTagToken:
+			if droppedElements[token.Data] {
+				if !token.SelfClosing && !voidElements[token.Data] {
+					dropping, dropDepth = token.Data, 1
+				}
+				continue
+			}
+			attributes, ok := allowedElements[token.Data]
+			if !ok {
+				continue
+			}
+			// 2) Write the element with its allowed attributes
+			blocked := writeStartTag(&buf, token, attributes, blockRemote, inlineURL)
+			remoteBlocked = remoteBlocked || blocked
+			if !voidElements[token.Data] {
+				open = append(open, token.Data)
+			}
+		case endTagToken:
+			// 3) Only close open elements (a stray end tag could close elements of the page)
+			for i := len(open) - 1; i >= 0; i-- {
+				if open[i] == token.Data {
+					for j := len(open) - 1; j >= i; j-- {
+						buf.WriteString("</" + open[j] + ">")
+					}
+					open = open[:i]
+					break
+				}
+			}
+		}
+	}
+	for i := len(open) - 1; i >= 0; i-- {
+		buf.WriteString("</" + open[i] + ">")
+	}
+	return buf.String(), remoteBlocked
+}
+
+/**
+ * Sanitizes the HTML part of the mail content (see SanitizeHTML).
+ * @return Whether remote content has been removed from the HTML part
+ */
+func (c Content) Sanitize(blockRemote bool, inlineURL func(contentID string) string) bool {
+	part, ok := c["text/html"]
+	if !ok {
+		return false
+	}
+	part.Body, part.RemoteContentBlocked = SanitizeHTML(part.Body, blockRemote, inlineURL)
+	c["text/html"] = part
+	return part.RemoteContentBlocked
+}
+
+/**
+ * Allows remote content for all mails of the given sender.
+ * ATTENTION: The trusted senders are only kept in memory, i.e., they are forgotten, once the IMAP
+ * connection is closed (e.g., at logout or session timeout).
+ */
+func (mc *MailCon) TrustSender(address string) {
+	mc.trustMutex.Lock()
+	defer mc.trustMutex.Unlock()
+	if nil == mc.trustedSenders {
+		mc.trustedSenders = make(map[string]bool)
+	}
+	mc.trustedSenders[strings.ToLower(strings.TrimSpace(address))] = true
+}
+
+/**
+ * @return Whether remote content has been allowed for the given sender (see TrustSender)
+ */
+func (mc *MailCon) IsTrustedSender(address string) bool {
+	mc.trustMutex.Lock()
+	defer mc.trustMutex.Unlock()
+	return mc.trustedSenders[strings.ToLower(strings.TrimSpace(address))]
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////
+///									Private Sanitize Methods									 ///
+////////////////////////////////////////////////////////////////////////////////////////////////////
+
+/**
+ * Writes the given start tag with all allowed attributes, which have safe values.
+ * @return Whether a remote image has been removed
+ */
+func writeStartTag(buf *bytes.Buffer, token htmlToken, allowed []string, blockRemote bool,
+	inlineURL func(contentID string) string) bool {
+	var remoteBlocked bool
+	buf.WriteString("<" + token.Data)
+	for _, attr := range token.Attributes {
+		if !containsString(allowed, attr.Name) && !containsString(globalAttributes, attr.Name) {
+			continue
+		}
+		var value string = attr.Value
+		switch attr.Name {
+		case "href":
+			if value = sanitizeURL(value, []string{"http", "https", "mailto"}); 0 == len(value) {
+				continue
+			}
+		case "src":
+			value = sanitizeURL(value, []string{"http", "https", "cid", "data"})
+			if isRemoteURL(value) && blockRemote {
+				remoteBlocked = true
+				continue
+			} else if strings.HasPrefix(strings.ToLower(value), "data:") &&
+				!isInlineImage(value) {
+				continue
+			} else if strings.HasPrefix(strings.ToLower(value), CID_SCHEME) && nil != inlineURL {
+				value = inlineURL(contentIDFromURL(value))
+			}
+			if 0 == len(value) {
+				continue
+			}
+		case "style":
+			if value = sanitizeStyle(value); 0 == len(value) {
+				continue
+			}
+		}
+		buf.WriteString(" " + attr.Name + "=\"" + html.EscapeString(value) + "\"")
+	}
+	if "a" == token.Data {
+		buf.WriteString(" target=\"_blank\" rel=\"noopener noreferrer\"")
+	}
+	if voidElements[token.Data] {
+		buf.WriteString("/")
+	}
+	buf.WriteString(">")
+	return remoteBlocked
+}
+
+/**
+ * @return The given URL, if it uses one of the given schemes, otherwise an empty string (relative
+ *		   URLs are removed, since they would point to this server)
+ */
+func sanitizeURL(url string, schemes []string) string {
+	// Browsers ignore whitespace and control characters within the scheme, e.g., "java\tscript:"
+	var cleaned string = strings.Map(func(r rune) rune {
+		if r <= 0x20 || 0x7f == r {
+			return -1
+		}
+		return r
+	}, url)
+	colon := strings.Index(cleaned, ":")
+	if colon <= 0 || !containsString(schemes, strings.ToLower(cleaned[:colon])) {
+		return ""
+	}
+	return strings.TrimSpace(url)
+}
+
+/**
+ * @return Whether the given URL loads content from a remote server
+ */
+func isRemoteURL(url string) bool {
+	var lower string = strings.ToLower(url)
+	return strings.HasPrefix(lower, "http:") || strings.HasPrefix(lower, "https:")
+}
+
+/**
+ * @return Whether the given data URL contains a raster image, e.g., "data:image/png;base64,..."
+ */
+func isInlineImage(url string) bool {
+	var lower string = strings.ToLower(url)
+	for _, mediaType := range []string{"image/png", "image/gif", "image/jpeg", "image/webp"} {
+		if strings.HasPrefix(lower, "data:"+mediaType+";") ||
+			strings.HasPrefix(lower, "data:"+mediaType+",") {
+			return true
+		}
+	}
+	return false
+}
+
+/**
+ * @return The declarations of the given style attribute, whose properties are allowed and whose
+ *		   values don't load content or execute code, e.g.,
+ *		   "color: red; position: fixed; background: url(x)" -> "color: red"
+ */
+func sanitizeStyle(style string) string {
+	var declarations []string
+	for _, declaration := range strings.Split(style, ";") {
+		parts := strings.SplitN(declaration, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		var (
+			property string = strings.ToLower(strings.TrimSpace(parts[0]))
+			value    string = strings.TrimSpace(parts[1])
+			lower    string = strings.ToLower(value)
+		)
+		if !allowedCSSProperties[property] || 0 == len(value) ||
+			strings.ContainsAny(value, "\\<>\"") || strings.Contains(lower, "url(") ||
+			strings.Contains(lower, "expression") || strings.Contains(lower, "script") {
+			continue
+		}
+		declarations = append(declarations, property+": "+value)
+	}
+	return strings.Join(declarations, "; ")
+}
+
+/**
+ * @return The Content-ID of the given cid: URL (RFC 2392), e.g., "cid:logo%40mars.com" ->
+ *		   "logo@mars.com"
+ */
+func contentIDFromURL(cidURL string) string {
+	var contentID string = strings.Trim(cidURL[len(CID_SCHEME):], "<>")
+	// A '+' within the URL isn't a space
+	if unescaped, err := url.QueryUnescape(strings.Replace(contentID, "+", "%2B", -1)); err == nil {
+		return unescaped
+	}
+	return contentID
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+/**
+ * @return The next token of the document and false, if the end of the document has been reached
+ */
+func (t *htmlTokenizer) next() (htmlToken, bool) {
+	if t.pos >= len(t.input) {
+		return htmlToken{}, false
+	}
+	// 1) The content of raw text elements ends only with their end tag
+	if len(t.rawText) > 0 {
+		var (
+			name  string = t.rawText
+			start int    = t.pos
+			end   int    = indexFold(t.input[t.pos:], "</"+name)
+		)
+		t.rawText = ""
+		if end < 0 {
+			t.pos = len(t.input)
+			return htmlToken{Kind: ignoredToken, Data: t.input[start:]}, true
+		}
+		t.pos += end
+		if end > 0 {
+			return htmlToken{Kind: ignoredToken, Data: t.input[start:t.pos]}, true
+		}
+	}
+	// 2) Text up to the next tag ('<' followed by a letter, '/', '!' or '?')
+	var start int = t.pos
+	for t.pos < len(t.input) {
+		if '<' == t.input[t.pos] && t.pos+1 < len(t.input) {
+			var c byte = t.input[t.pos+1]
+			if isASCIILetter(c) || '/' == c || '!' == c || '?' == c {
+				break
+			}
+		}
+		t.pos++
+	}
+	if t.pos > start {
+		return htmlToken{Kind: textToken, Data: escapeText(t.input[start:t.pos])}, true
+	}
+	// 3) Comments, doctype declarations and processing instructions
+	switch t.input[t.pos+1] {
+	case '!':
+		if strings.HasPrefix(t.input[t.pos:], "<!--") {
+			if end := strings.Index(t.input[t.pos+4:], "-->"); end >= 0 {
+				t.pos += 4 + end + 3
+			} else {
+				t.pos = len(t.input)
+			}
+			return htmlToken{Kind: ignoredToken}, true
+		}
+		fallthrough
+	case '?':
+		if end := strings.IndexByte(t.input[t.pos:], '>'); end >= 0 {
+			t.pos += end + 1
+		} else {
+			t.pos = len(t.input)
+		}
+		return htmlToken{Kind: ignoredToken}, true
+	}
+	return t.readTag(), true
+}
+
+/**
+ * Reads the start or end tag at the current position.
+ */
+func (t *htmlTokenizer) readTag() htmlToken {
+	var token htmlToken = htmlToken{Kind: startTagToken}
+	t.pos++
+	if '/' == t.input[t.pos] {
+		token.Kind = endTagToken
+		t.pos++
+	}
+	// 1) Tag name
+	var start int = t.pos
+	for t.pos < len(t.input) && !isHTMLSpace(t.input[t.pos]) && t.input[t.pos] != '>' &&
+		t.input[t.pos] != '/' {
+		t.pos++
+	}
+	token.Data = strings.ToLower(t.input[start:t.pos])
+	// 2) Attributes up to the end of the tag
+	for t.pos < len(t.input) {
+		var c byte = t.input[t.pos]
+		switch {
+		case '>' == c:
+			t.pos++
+			if startTagToken == token.Kind && rawTextElements[token.Data] && !token.SelfClosing {
+				t.rawText = token.Data
+			}
+			return token
+		case '/' == c:
+			t.pos++
+			token.SelfClosing = t.pos < len(t.input) && '>' == t.input[t.pos]
+		case isHTMLSpace(c):
+			t.pos++
+		default:
+			if attr, ok := t.readAttribute(); ok && endTagToken != token.Kind {
+				token.Attributes = append(token.Attributes, attr)
+			}
+		}
+	}
+	return token
+}
+
+/**
+ * Reads the attribute at the current position, e.g., href="http://mars.com" | nowrap
+ * @return The attribute and false, if the attribute name is invalid
+ */
+func (t *htmlTokenizer) readAttribute() (htmlAttribute, bool) {
+	var (
+		attr  htmlAttribute
+		start int = t.pos
+	)
+	// 1) Attribute name (the first character may be '=' as in <a =x>)
+	t.pos++
+	for t.pos < len(t.input) && !isHTMLSpace(t.input[t.pos]) &&
+		!strings.ContainsRune("=/>", rune(t.input[t.pos])) {
+		t.pos++
+	}
+	attr.Name = strings.ToLower(t.input[start:t.pos])
+	for t.pos < len(t.input) && isHTMLSpace(t.input[t.pos]) {
+		t.pos++
+	}
+	if t.pos >= len(t.input) || t.input[t.pos] != '=' {
+		return attr, isValidAttributeName(attr.Name)
+	}
+	// 2) Quoted or unquoted value
+	t.pos++
+	for t.pos < len(t.input) && isHTMLSpace(t.input[t.pos]) {
+		t.pos++
+	}
+	if t.pos < len(t.input) && ('"' == t.input[t.pos] || '\'' == t.input[t.pos]) {
+		var quote byte = t.input[t.pos]
+		t.pos++
+		start = t.pos
+		for t.pos < len(t.input) && t.input[t.pos] != quote {
+			t.pos++
+		}
+		attr.Value = html.UnescapeString(t.input[start:t.pos])
+		if t.pos < len(t.input) {
+			t.pos++
+		}
+	} else {
+		start = t.pos
+		for t.pos < len(t.input) && !isHTMLSpace(t.input[t.pos]) && t.input[t.pos] != '>' {
+			t.pos++
+		}
+		attr.Value = html.UnescapeString(t.input[start:t.pos])
+	}
+	return attr, isValidAttributeName(attr.Name)
+}
+
+/**
+ * @return The given text with all '<' escaped, which don't start a tag (the rest of the text is
+ *		   kept as is, since it may contain entities)
+ */
+func escapeText(text string) string {
+	return strings.Replace(text, "<", "&lt;", -1)
+}
+
+func isValidAttributeName(name string) bool {
+	if 0 == len(name) {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		if !isASCIILetter(name[i]) && '-' != name[i] && (name[i] < '0' || name[i] > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+func isASCIILetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isHTMLSpace(c byte) bool {
+	return ' ' == c || '\t' == c || '\n' == c || '\r' == c || '\f' == c
+}
+
+/**
+ * @return The index of the first occurrence of the ASCII string 'substr' in 's' ignoring the case
+ *		   or -1
+ */
+func indexFold(s, substr string) int {
+	for i := 0; i+len(substr) <= len(s); i++ {
+		if strings.EqualFold(s[i:i+len(substr)], substr) {
+			return i
+		}
+	}
+	return -1
+}
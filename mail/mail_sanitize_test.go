@@ -0,0 +1,74 @@
+package mail
+
+import (
+	"testing"
+)
+
+func TestSanitizeHTML(t *testing.T) {
+	for _, test := range []struct {
+		html     string
+		expected string
+	}{
+		{`<p onclick="alert(1)" style="color: red; position: fixed">Hi<script>alert("</p>")` +
+			`</script></p>`, `<p style="color: red">Hi</p>`},
+		{`<a href="jav&#x61;script:alert(1)">x</a><a href=http://mars.com?a=1&b=2>y</a>`,
+			`<a target="_blank" rel="noopener noreferrer">x</a><a href="http://mars.com?a=1&amp;b=2"` +
+				` target="_blank" rel="noopener noreferrer">y</a>`},
+		{`<html><head><title>T</title><style>body {}</style></head><BODY>a < b</div>` +
+			`<form action="/logout"><input type="submit"><b>bold</form>`,
+			`a &lt; b<b>bold</b>`},
+		{`<!-- <b> --><!DOCTYPE html><img src="cid:logo" alt="Logo"><iframe src="x"></iframe>`,
+			`<img src="cid:logo" alt="Logo"/>`},
+		{`<div style="background: url(http://t.com/p.gif)"><img src="data:text/html,x"></div>`,
+			`<div><img/></div>`},
+	} {
+		if html, _ := SanitizeHTML(test.html, true, nil); html != test.expected {
+			t.Errorf("Expected '%s' for '%s', but got '%s'", test.expected, test.html, html)
+		}
+	}
+}
+
+func TestBlockRemoteContent(t *testing.T) {
+	const body string = `<img src="https://t.com/pixel.gif" width="1">`
+	if html, blocked := SanitizeHTML(body, true, nil); !blocked || html != `<img width="1"/>` {
+		t.Errorf("Remote image hasn't been blocked: '%s'", html)
+	}
+	if html, blocked := SanitizeHTML(body, false, nil); blocked ||
+		html != `<img src="https://t.com/pixel.gif" width="1"/>` {
+		t.Errorf("Remote image has been blocked although allowed: '%s'", html)
+	}
+	content := Content{"text/html": ContentPart{Body: body}, "text/plain": ContentPart{Body: "<x>"}}
+	if !content.Sanitize(true, nil) || !content["text/html"].RemoteContentBlocked ||
+		content["text/plain"].Body != "<x>" {
+		t.Errorf("Content hasn't been sanitized correctly: %v", content)
+	}
+}
+
+func TestInlineImages(t *testing.T) {
+	const related string = "--rel\r\nContent-Type: text/html; charset=utf-8\r\n\r\n" +
+		"<img src=\"cid:logo%40mars.com\"><img src=\"cid:unknown\">\r\n" +
+		"--rel\r\nContent-Type: image/png\r\nContent-Transfer-Encoding: base64\r\n" +
+		"Content-ID: <logo@mars.com>\r\n\r\niVBORw0KGgo=\r\n--rel--\r\n"
+	parts, err := parseMultipartParts(related, "rel", "")
+	if err != nil {
+		t.Fatalf("Couldn't parse multipart/related: %s", err.Error())
+	}
+	var (
+		m       *Mail   = &Mail{UID: 7, Parts: &MailPart{Children: parts}}
+		content Content = Content{}
+	)
+	collectContent(parts, content)
+	if _, ok := content["image/png"]; ok || len(content) != 1 {
+		t.Errorf("Inline image has been collected as content: %v", content)
+	}
+	if part := m.Parts.FindContentID("logo@mars.com"); nil == part || part.Section != "2" ||
+		!part.IsInlineImage() {
+		t.Errorf("Inline image hasn't been found by its Content-ID: %v", part)
+	}
+	content.Sanitize(true, m.InlineURLs(func(contentID string) string {
+		return "/inline?cid=" + contentID
+	}))
+	if body := content["text/html"].Body; body != `<img src="/inline?cid=logo@mars.com"/><img/>` {
+		t.Errorf("cid: references haven't been rewritten: '%s'", body)
+	}
+}
@@ -0,0 +1,198 @@
+package mail
+
+import (
+	"github.com/mxk/go-imap/imap"
+	"sort"
+	"time"
+)
+
+// The date format used by the IMAP SEARCH command (RFC 3501: date)
+const SEARCH_DATE_FORMAT string = "2-Jan-2006"
+
+// A structured search query, which is compiled into the criteria of an IMAP SEARCH command. All
+// given criteria have to match (AND), empty criteria are ignored.
+type SearchQuery struct {
+	// Mails whose sender contains the given string (FROM)
+	From string
+	// Mails whose receivers contain the given string (TO)
+	To string
+	// Mails whose carbon copy receivers contain the given string (CC)
+	Cc string
+	// Mails whose subject contains the given string (SUBJECT)
+	Subject string
+	// Mails whose body contains the given string (BODY)
+	Body string
+	// Mails whose header or body contains the given string (TEXT)
+	Text string
+	// Mails received on or after the given date (SINCE)
+	Since time.Time
+	// Mails received before the given date (BEFORE)
+	Before time.Time
+	// Mails larger than the given number of bytes (LARGER)
+	Larger uint32
+	// Mails smaller than the given number of bytes (SMALLER)
+	Smaller uint32
+	// Mails that have all flags set, which are true in the given Flags, e.g., SEEN, FLAGGED
+	WithFlags *Flags
+	// Mails that have none of the flags set, which are true in the given Flags, e.g., UNSEEN
+	WithoutFlags *Flags
+	// Mails whose header field contains the given string: header field name -> value (HEADER)
+	Header map[string]string
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////
+///									Public Search Methods										 ///
+////////////////////////////////////////////////////////////////////////////////////////////////////
+
+/**
+ * Searches the given folder for all mails matching the given query (UID SEARCH).
+ * @return The UIDs of all matching mails
+ */
+func (mc *MailCon) Search(folder string, query *SearchQuery) ([]uint32, error) {
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+	return mc.search_internal(folder, query)
+}
+
+/**
+ * Searches all given folders for mails matching the given query.
+ * @return The matching mails of all folders without their content (UID, Header and Flags are set),
+ *		   newest first.
+ */
+func (mc *MailCon) SearchMails(folders []string, query *SearchQuery) ([]Mail, error) {
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+	var mails []Mail = []Mail{}
+	for _, folder := range folders {
+		uids, err := mc.search_internal(folder, query)
+		if err != nil {
+			return mails, err
+		}
+		if 0 == len(uids) {
+			continue
+		}
+		set := new(imap.SeqSet)
+		set.AddNum(uids...)
+		folderMails, err := mc.loadMails(set, folder, false, mc.client.UIDFetch)
+		if err != nil {
+			return mails, err
+		}
+		mails = append(mails, folderMails...)
+	}
+	sort.Sort(MailSlice(mails))
+	return mails, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////
+///									Private Search Methods										 ///
+////////////////////////////////////////////////////////////////////////////////////////////////////
+
+/**
+ * ATTENTION: DOES NOT LOCK THE IMAP CONNECTION! => Has to be wrapped into a mutex lock method
+ */
+func (mc *MailCon) search_internal(folder string, query *SearchQuery) ([]uint32, error) {
+	if err := mc.selectFolder(folder, true); err != nil {
+		return nil, err
+	}
+	var (
+		cmd  *imap.Command
+		uids []uint32 = []uint32{}
+		err  error
+	)
+	if cmd, err = mc.waitFor(mc.client.UIDSearch(query.compile(mc.client.Quote)...)); err != nil {
+		return nil, err
+	}
+	for _, resp := range cmd.Data {
+		uids = append(uids, resp.SearchResults()...)
+	}
+	mc.clearData()
+	return uids, nil
+}
+
+/**
+ * Compiles the query into the search criteria of the IMAP SEARCH command, e.g.:
+ *	{From: "mark", Since: 2015-09-01, WithoutFlags: {Seen}} -> FROM "mark" SINCE 1-Sep-2015 UNSEEN
+ * @param quote Function to quote strings (non-ASCII strings are sent as literals)
+ * @return The criteria without the CHARSET, which is always set to UTF-8 by imap.Client.UIDSearch
+ */
+func (q *SearchQuery) compile(quote func(v interface{}) imap.Field) []imap.Field {
+	var criteria []imap.Field = []imap.Field{}
+	addString := func(key, value string) {
+		if 0 == len(value) {
+			return
+		}
+		criteria = append(criteria, key, quote(value))
+	}
+	addString("FROM", q.From)
+	addString("TO", q.To)
+	addString("CC", q.Cc)
+	addString("SUBJECT", q.Subject)
+	addString("BODY", q.Body)
+	addString("TEXT", q.Text)
+	if !q.Since.IsZero() {
+		criteria = append(criteria, "SINCE", q.Since.Format(SEARCH_DATE_FORMAT))
+	}
+	if !q.Before.IsZero() {
+		criteria = append(criteria, "BEFORE", q.Before.Format(SEARCH_DATE_FORMAT))
+	}
+	if q.Larger > 0 {
+		criteria = append(criteria, "LARGER", q.Larger)
+	}
+	if q.Smaller > 0 {
+		criteria = append(criteria, "SMALLER", q.Smaller)
+	}
+	if nil != q.WithFlags {
+		criteria = append(criteria, flagCriteria(q.WithFlags, false)...)
+	}
+	if nil != q.WithoutFlags {
+		criteria = append(criteria, flagCriteria(q.WithoutFlags, true)...)
+	}
+	// Sort the header fields to create a deterministic search command
+	var fields []string = make([]string, 0, len(q.Header))
+	for field := range q.Header {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		criteria = append(criteria, "HEADER", quote(field), quote(q.Header[field]))
+	}
+	if 0 == len(criteria) {
+		return []imap.Field{"ALL"}
+	}
+	return criteria
+}
+
+/**
+ * @param negate False - Criteria for mails, that have the flags set, e.g., SEEN
+ *				 True  - Criteria for mails, that don't have the flags set, e.g., UNSEEN
+ */
+func flagCriteria(f *Flags, negate bool) []imap.Field {
+	var criteria []imap.Field = []imap.Field{}
+	add := func(set bool, key, negatedKey string) {
+		if !set {
+			return
+		}
+		if negate {
+			criteria = append(criteria, negatedKey)
+		} else {
+			criteria = append(criteria, key)
+		}
+	}
+	add(f.Seen, "SEEN", "UNSEEN")
+	add(f.Answered, "ANSWERED", "UNANSWERED")
+	add(f.Deleted, "DELETED", "UNDELETED")
+	add(f.Flagged, "FLAGGED", "UNFLAGGED")
+	add(f.Draft, "DRAFT", "UNDRAFT")
+	add(f.Recent, "RECENT", "OLD")
+	for _, keyword := range f.Keywords {
+		if !isValidKeyword(keyword) {
+			continue
+		}
+		if negate {
+			criteria = append(criteria, "UNKEYWORD", keyword)
+		} else {
+			criteria = append(criteria, "KEYWORD", keyword)
+		}
+	}
+	return criteria
+}
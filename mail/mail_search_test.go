@@ -0,0 +1,70 @@
+package mail
+
+import (
+	"fmt"
+	"github.com/mxk/go-imap/imap"
+	"github.com/mxk/go-imap/mock"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func quoteForTest(v interface{}) imap.Field {
+	return strconv.Quote(fmt.Sprint(v))
+}
+
+func TestCompileSearchQuery(t *testing.T) {
+	since, _ := time.Parse("2006-01-02", "2015-09-01")
+	query := &SearchQuery{
+		From:         "mark",
+		Subject:      "potatoes",
+		Since:        since,
+		Larger:       1024,
+		WithFlags:    &Flags{Flagged: true},
+		WithoutFlags: &Flags{Seen: true},
+		Header:       map[string]string{"X-Mailer": "watney"},
+	}
+	expected := []imap.Field{`FROM`, `"mark"`, `SUBJECT`, `"potatoes"`, `SINCE`, `1-Sep-2015`,
+		`LARGER`, uint32(1024), `FLAGGED`, `UNSEEN`, `HEADER`, `"X-Mailer"`, `"watney"`}
+	if criteria := query.compile(quoteForTest); !reflect.DeepEqual(criteria, expected) {
+		t.Fatalf("Expected search criteria %v, but got %v", expected, criteria)
+	}
+}
+
+func TestCompileEmptyAndUTF8SearchQuery(t *testing.T) {
+	if criteria := (&SearchQuery{}).compile(quoteForTest); !reflect.DeepEqual(criteria,
+		[]imap.Field{"ALL"}) {
+		t.Fatalf("Expected empty query to search for ALL mails, but got %v", criteria)
+	}
+	// The CHARSET is added by imap.Client.UIDSearch
+	criteria := (&SearchQuery{Subject: "Kartoffelsalat für alle"}).compile(quoteForTest)
+	if len(criteria) != 2 || criteria[0] != "SUBJECT" {
+		t.Fatalf("Expected non-ASCII query without CHARSET, but got %v", criteria)
+	}
+}
+
+func TestSearchUTF8Command(T *testing.T) {
+	t := mock.Server(T,
+		`S: * PREAUTH [CAPABILITY IMAP4rev1] Server ready`,
+	)
+	c, err := t.Dial()
+	t.Join(err)
+	var mc *MailCon = &MailCon{client: c, mailbox: DFLT_MAILBOX_NAME, delim: "/",
+		uidStates: make(uidTracker)}
+	t.Script(
+		`C: A1 SELECT "INBOX"`,
+		`S: * 2 EXISTS`,
+		`S: A1 OK [READ-WRITE] SELECT completed`,
+		`C: A2 UID SEARCH CHARSET UTF-8 SUBJECT {7}`,
+		`S: + Ready for literal data`,
+		`C: Grüße`,
+		`S: * SEARCH 4`,
+		`S: A2 OK SEARCH completed`,
+	)
+	uids, err := mc.search_internal("/", &SearchQuery{Subject: "Grüße"})
+	t.Join(err)
+	if !reflect.DeepEqual(uids, []uint32{4}) {
+		t.Errorf("Expected UID 4, but got %v", uids)
+	}
+}
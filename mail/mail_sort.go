@@ -0,0 +1,348 @@
+package mail
+
+import (
+	"fmt"
+	"github.com/mxk/go-imap/imap"
+	"sort"
+	"strings"
+)
+
+// Criteria to sort mails by (RFC 5256: sort-key)
+const (
+	SORT_DATE    string = "DATE"    // The sent date of the mail (Date:)
+	SORT_ARRIVAL string = "ARRIVAL" // The time the mail has arrived in the folder
+	SORT_FROM    string = "FROM"    // The sender of the mail (From:)
+	SORT_SUBJECT string = "SUBJECT" // The base subject of the mail (without "Re:", "Fwd:", ...)
+	SORT_SIZE    string = "SIZE"    // The size of the mail
+)
+
+// Algorithms to group mails into threads (RFC 5256: thread-alg)
+const (
+	// Groups mails by their base subject, whereas all replies are children of the first mail
+	THREAD_ORDEREDSUBJECT string = "ORDEREDSUBJECT"
+	// Groups mails by their Message-ID, In-Reply-To and References headers
+	THREAD_REFERENCES string = "REFERENCES"
+)
+
+// One node in a thread tree. The root node of a thread is the first mail of a discussion.
+type ThreadNode struct {
+	// The UID of the mail (0 = the mail is missing, e.g., because it has been deleted)
+	UID uint32
+	// The Message-ID of the mail (only known, if the thread has been built locally)
+	MessageID string
+	// The mail of this node without its content (nil, if the mail is missing)
+	Mail *Mail
+	// All replies to the mail of this node
+	Children []*ThreadNode
+}
+
+// All supported sort criteria -> function comparing two mails in ascending order
+var sortCriteria = map[string]func(a, b *Mail) bool{
+	SORT_DATE:    func(a, b *Mail) bool { return a.Header.Date.Before(b.Header.Date) },
+	SORT_ARRIVAL: func(a, b *Mail) bool { return a.UID < b.UID },
+	SORT_FROM: func(a, b *Mail) bool {
+		return strings.ToLower(a.Header.Sender) < strings.ToLower(b.Header.Sender)
+	},
+	SORT_SUBJECT: func(a, b *Mail) bool {
+		return baseSubject(a.Header.Subject) < baseSubject(b.Header.Subject)
+	},
+	SORT_SIZE: func(a, b *Mail) bool { return a.Header.Size < b.Header.Size },
+}
+
+// Sorts mails by one of the sortCriteria (stable, to keep the arrival order of equal mails)
+type mailSorter struct {
+	mails []Mail
+	less  func(a, b *Mail) bool
+}
+
+func (s mailSorter) Len() int           { return len(s.mails) }
+func (s mailSorter) Less(i, j int) bool { return s.less(&s.mails[i], &s.mails[j]) }
+func (s mailSorter) Swap(i, j int)      { s.mails[i], s.mails[j] = s.mails[j], s.mails[i] }
+
+////////////////////////////////////////////////////////////////////////////////////////////////////
+///									Public Sort Methods											 ///
+////////////////////////////////////////////////////////////////////////////////////////////////////
+
+/**
+ * Loads all mails of the given folder sorted by the given criterion. The sorting is done by the
+ * server (UID SORT), if it supports the SORT extension, and locally otherwise (or if the server
+ * fails to sort the mails).
+ * @param criterion One of SORT_DATE, SORT_ARRIVAL, SORT_FROM, SORT_SUBJECT, SORT_SIZE
+ * @param reverse	False - Ascending order (e.g., oldest mail first)
+ *					True - Descending order (e.g., newest mail first)
+ * @param withContent	True - Also loads the content of all mails
+ *						False - Only loads the headers of all mails
+ */
+func (mc *MailCon) SortMails(folder, criterion string, reverse, withContent bool) ([]Mail,
+	error) {
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+	criterion = strings.ToUpper(criterion)
+	less, ok := sortCriteria[criterion]
+	if !ok {
+		return []Mail{}, fmt.Errorf("Unknown sort criterion '%s'", criterion)
+	}
+	// 1) Let the server sort the mails, if possible
+	if mc.client.Caps["SORT"] {
+		uids, err := mc.sort_internal(folder, criterion, reverse)
+		if err == nil {
+			return mc.loadMailsByUID(folder, uids, withContent)
+		}
+		mc.logMC(fmt.Sprintf("Server couldn't sort folder '%s', sorting locally: %s", folder,
+			err.Error()), imap.LogAll)
+	}
+	// 2) Otherwise load all mails and sort them locally
+	set, _ := imap.NewSeqSet("1:*")
+	mails, err := mc.loadMails(set, folder, withContent, mc.client.Fetch)
+	if err != nil {
+		return []Mail{}, err
+	}
+	sortMails(mails, less, reverse)
+	return mails, nil
+}
+
+/**
+ * Groups all mails of the given folder into threads. The threads are built by the server (UID
+ * THREAD), if it supports the THREAD extension with the given algorithm, and locally otherwise
+ * (or if the server fails to build the threads).
+ * @param algorithm One of THREAD_REFERENCES, THREAD_ORDEREDSUBJECT
+ * @return The root nodes of all threads, ordered by the date of their first mail (oldest first).
+ *		   The mails of all nodes are loaded without their content.
+ */
+func (mc *MailCon) ThreadMails(folder, algorithm string) ([]*ThreadNode, error) {
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+	algorithm = strings.ToUpper(algorithm)
+	if algorithm != THREAD_REFERENCES && algorithm != THREAD_ORDEREDSUBJECT {
+		return []*ThreadNode{}, fmt.Errorf("Unknown thread algorithm '%s'", algorithm)
+	}
+	set, _ := imap.NewSeqSet("1:*")
+	mails, err := mc.loadMails(set, folder, false, mc.client.Fetch)
+	if err != nil {
+		return []*ThreadNode{}, err
+	}
+	// 1) Let the server build the threads, if possible
+	if mc.client.Caps["THREAD="+algorithm] {
+		threads, err := mc.thread_internal(folder, algorithm)
+		if err == nil {
+			byUID := make(map[uint32]*Mail, len(mails))
+			for i := range mails {
+				byUID[mails[i].UID] = &mails[i]
+			}
+			for _, thread := range threads {
+				thread.attachMails(byUID)
+			}
+			return threads, nil
+		}
+		mc.logMC(fmt.Sprintf("Server couldn't thread folder '%s', threading locally: %s", folder,
+			err.Error()), imap.LogAll)
+	}
+	// 2) Otherwise group the mails locally
+	if algorithm == THREAD_REFERENCES {
+		return buildThreads(mails), nil
+	}
+	return threadBySubject(mails), nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////
+///									Private Sort Methods										 ///
+////////////////////////////////////////////////////////////////////////////////////////////////////
+
+/**
+ * ATTENTION: DOES NOT LOCK THE IMAP CONNECTION! => Has to be wrapped into a mutex lock method
+ * @return The UIDs of all mails in the given folder in the requested order
+ */
+func (mc *MailCon) sort_internal(folder, criterion string, reverse bool) ([]uint32, error) {
+	if err := mc.selectFolder(folder, true); err != nil {
+		return nil, err
+	}
+	var (
+		cmd      *imap.Command
+		criteria []imap.Field = []imap.Field{criterion}
+		uids     []uint32     = []uint32{}
+		err      error
+	)
+	if reverse {
+		criteria = []imap.Field{"REVERSE", criterion}
+	}
+	if cmd, err = mc.waitFor(mc.client.Send("UID SORT", criteria, "UTF-8", "ALL")); err != nil {
+		return nil, err
+	}
+	for _, resp := range cmd.Data {
+		if strings.ToUpper(resp.Label) == "SORT" {
+			for _, field := range resp.Fields[1:] {
+				uids = append(uids, imap.AsNumber(field))
+			}
+		}
+	}
+	mc.clearData()
+	return uids, nil
+}
+
+/**
+ * ATTENTION: DOES NOT LOCK THE IMAP CONNECTION! => Has to be wrapped into a mutex lock method
+ * @return The thread trees of all mails in the given folder (only UIDs are set)
+ */
+func (mc *MailCon) thread_internal(folder, algorithm string) ([]*ThreadNode, error) {
+	if err := mc.selectFolder(folder, true); err != nil {
+		return nil, err
+	}
+	var (
+		cmd     *imap.Command
+		threads []*ThreadNode = []*ThreadNode{}
+		err     error
+	)
+	if cmd, err = mc.waitFor(mc.client.Send("UID THREAD", algorithm, "UTF-8", "ALL")); err != nil {
+		return nil, err
+	}
+	for _, resp := range cmd.Data {
+		if strings.ToUpper(resp.Label) == "THREAD" {
+			threads = append(threads, parseThreads(resp.Fields[1:])...)
+		}
+	}
+	mc.clearData()
+	return threads, nil
+}
+
+/**
+ * Loads the mails with the given UIDs from the given folder in the order of the given UIDs.
+ * ATTENTION: DOES NOT LOCK THE IMAP CONNECTION! => Has to be wrapped into a mutex lock method
+ */
+func (mc *MailCon) loadMailsByUID(folder string, uids []uint32, withContent bool) ([]Mail,
+	error) {
+	if 0 == len(uids) {
+		return []Mail{}, nil
+	}
+	set := new(imap.SeqSet)
+	set.AddNum(uids...)
+	mails, err := mc.loadMails(set, folder, withContent, mc.client.UIDFetch)
+	if err != nil {
+		return []Mail{}, err
+	}
+	byUID := make(map[uint32]Mail, len(mails))
+	for _, curMail := range mails {
+		byUID[curMail.UID] = curMail
+	}
+	var ordered []Mail = make([]Mail, 0, len(mails))
+	for _, uid := range uids {
+		if curMail, ok := byUID[uid]; ok {
+			ordered = append(ordered, curMail)
+		}
+	}
+	return ordered, nil
+}
+
+/**
+ * Sorts the given mails with the given comparison function (see sortCriteria).
+ */
+func sortMails(mails []Mail, less func(a, b *Mail) bool, reverse bool) {
+	if reverse {
+		sort.Stable(mailSorter{mails, func(a, b *Mail) bool { return less(b, a) }})
+	} else {
+		sort.Stable(mailSorter{mails, less})
+	}
+}
+
+/**
+ * Parses the thread lists of a THREAD response (RFC 5256), e.g.:
+ *	(2)(3 6 (4 23)(44 7 96)) -> 2 | 3 -> 6 -> {4 -> 23, 44 -> 7 -> 96}
+ * @return One root node for each thread list
+ */
+func parseThreads(fields []imap.Field) []*ThreadNode {
+	var threads []*ThreadNode = []*ThreadNode{}
+	for _, field := range fields {
+		if list := imap.AsList(field); len(list) > 0 {
+			threads = append(threads, parseThreadList(list))
+		}
+	}
+	return threads
+}
+
+/**
+ * Each number of a thread list is the child of its predecessor, whereas nested lists at the end
+ * are sibling sub-threads of the last number. A list, that starts with a nested list, has a
+ * missing (dummy) root mail.
+ */
+func parseThreadList(list []imap.Field) *ThreadNode {
+	var root, cur *ThreadNode
+	for _, field := range list {
+		if sub := imap.AsList(field); nil != sub {
+			if nil == cur {
+				root = &ThreadNode{}
+				cur = root
+			}
+			cur.Children = append(cur.Children, parseThreadList(sub))
+			continue
+		}
+		node := &ThreadNode{UID: imap.AsNumber(field)}
+		if nil == cur {
+			root = node
+		} else {
+			cur.Children = append(cur.Children, node)
+		}
+		cur = node
+	}
+	return root
+}
+
+/**
+ * Sets the mail of this node and all its children from the given UID -> Mail map.
+ */
+func (t *ThreadNode) attachMails(byUID map[uint32]*Mail) {
+	if nil == t {
+		return
+	}
+	t.Mail = byUID[t.UID]
+	for _, child := range t.Children {
+		child.attachMails(byUID)
+	}
+}
+
+/**
+ * Groups the given mails into threads by their base subject (ORDEREDSUBJECT): the oldest mail of
+ * each subject is the root of the thread and all other mails are its children (sorted by date).
+ * @return The threads ordered by the date of their root mail
+ */
+func threadBySubject(mails []Mail) []*ThreadNode {
+	sortMails(mails, sortCriteria[SORT_DATE], false)
+	var (
+		threads   []*ThreadNode          = []*ThreadNode{}
+		bySubject map[string]*ThreadNode = make(map[string]*ThreadNode)
+	)
+	for i := range mails {
+		node := &ThreadNode{UID: mails[i].UID, Mail: &mails[i]}
+		subject := baseSubject(mails[i].Header.Subject)
+		if root, ok := bySubject[subject]; ok {
+			root.Children = append(root.Children, node)
+		} else {
+			bySubject[subject] = node
+			threads = append(threads, node)
+		}
+	}
+	return threads
+}
+
+/**
+ * Extracts the base subject of the given subject (RFC 5256: base subject), i.e., removes all
+ * reply and forward prefixes and trailers, e.g.: "Re: [watney] Fwd: Potatoes (fwd)" -> "potatoes"
+ */
+func baseSubject(subject string) string {
+	var base string = strings.ToLower(strings.Join(strings.Fields(subject), " "))
+	for changed := true; changed; {
+		changed = false
+		if strings.HasSuffix(base, "(fwd)") {
+			base, changed = strings.TrimSpace(strings.TrimSuffix(base, "(fwd)")), true
+		}
+		for _, prefix := range []string{"re:", "fwd:", "fw:", "aw:", "wg:"} {
+			if strings.HasPrefix(base, prefix) {
+				base, changed = strings.TrimSpace(strings.TrimPrefix(base, prefix)), true
+			}
+		}
+		// Remove blobs like "[watney]" in front of the subject (unless it's the whole subject)
+		if end := strings.Index(base, "]"); strings.HasPrefix(base, "[") && end > 0 &&
+			end < len(base)-1 {
+			base, changed = strings.TrimSpace(base[end+1:]), true
+		}
+	}
+	return base
+}
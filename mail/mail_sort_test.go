@@ -0,0 +1,133 @@
+package mail
+
+import (
+	"github.com/mxk/go-imap/imap"
+	"github.com/mxk/go-imap/mock"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseThreads(t *testing.T) {
+	// (2)(3 6 (4 23)(44 7 96))((5)(8))
+	threads := parseThreads([]imap.Field{
+		[]imap.Field{uint32(2)},
+		[]imap.Field{uint32(3), uint32(6),
+			[]imap.Field{uint32(4), uint32(23)},
+			[]imap.Field{uint32(44), uint32(7), uint32(96)}},
+		[]imap.Field{[]imap.Field{uint32(5)}, []imap.Field{uint32(8)}},
+	})
+	if len(threads) != 3 {
+		t.Fatalf("Expected 3 threads, but got %d", len(threads))
+	}
+	if threads[0].UID != 2 || len(threads[0].Children) != 0 {
+		t.Errorf("Expected single mail thread 2, but got %v", threads[0])
+	}
+	six := threads[1].Children[0]
+	if threads[1].UID != 3 || six.UID != 6 || len(six.Children) != 2 ||
+		six.Children[0].UID != 4 || six.Children[0].Children[0].UID != 23 ||
+		six.Children[1].UID != 44 || six.Children[1].Children[0].Children[0].UID != 96 {
+		t.Errorf("Thread 3 has not been parsed correctly")
+	}
+	if threads[2].UID != 0 || len(threads[2].Children) != 2 || threads[2].Children[1].UID != 8 {
+		t.Errorf("Expected dummy root with children 5 and 8, but got %v", threads[2])
+	}
+}
+
+func TestBaseSubject(t *testing.T) {
+	for subject, expected := range map[string]string{
+		"Potatoes":                         "potatoes",
+		"Re: [watney] Fwd: Potatoes (fwd)": "potatoes",
+		"RE: re:  Mars   rover":            "mars rover",
+		"[watney]":                         "[watney]",
+	} {
+		if base := baseSubject(subject); base != expected {
+			t.Errorf("Expected base subject '%s' for '%s', but got '%s'", expected, subject, base)
+		}
+	}
+}
+
+func TestLocalSortAndThreads(t *testing.T) {
+	now := time.Now()
+	mails := []Mail{
+		{UID: 1, Header: &Header{Subject: "Re: Potatoes", Date: now, Size: 30}},
+		{UID: 2, Header: &Header{Subject: "Potatoes", Date: now.Add(-time.Hour), Size: 10}},
+		{UID: 3, Header: &Header{Subject: "Water", Date: now.Add(-time.Minute), Size: 20}},
+	}
+	sortMails(mails, sortCriteria[SORT_SIZE], true)
+	if mails[0].UID != 1 || mails[1].UID != 3 || mails[2].UID != 2 {
+		t.Errorf("Mails are not sorted by size in descending order")
+	}
+	threads := threadBySubject(mails)
+	if len(threads) != 2 || threads[0].UID != 2 || len(threads[0].Children) != 1 ||
+		threads[0].Children[0].UID != 1 || threads[1].UID != 3 {
+		t.Errorf("Mails are not threaded by subject correctly")
+	}
+}
+
+func TestServerSortAndThreads(T *testing.T) {
+	t := mock.Server(T,
+		`S: * PREAUTH [CAPABILITY IMAP4rev1 SORT THREAD=REFERENCES] Server ready`,
+	)
+	c, err := t.Dial()
+	t.Join(err)
+	registerCommands(c)
+	var mc *MailCon = &MailCon{client: c, mailbox: DFLT_MAILBOX_NAME, delim: "/",
+		uidStates: make(uidTracker)}
+	t.Script(
+		`C: A1 SELECT "INBOX"`,
+		`S: * 3 EXISTS`,
+		`S: A1 OK [READ-WRITE] SELECT completed`,
+		`C: A2 UID SORT (REVERSE DATE) UTF-8 ALL`,
+		`S: * SORT 5 3 4`,
+		`S: A2 OK Sort completed`,
+	)
+	uids, err := mc.sort_internal("/", SORT_DATE, true)
+	t.Join(err)
+	if !reflect.DeepEqual(uids, []uint32{5, 3, 4}) {
+		t.Errorf("Expected UIDs [5 3 4] sorted by the server, but got %v", uids)
+	}
+	t.Script(
+		`C: A3 SELECT "INBOX"`,
+		`S: * 3 EXISTS`,
+		`S: A3 OK [READ-WRITE] SELECT completed`,
+		`C: A4 UID THREAD REFERENCES UTF-8 ALL`,
+		`S: * THREAD (3)(4 5)`,
+		`S: A4 OK Thread completed`,
+	)
+	threads, err := mc.thread_internal("/", THREAD_REFERENCES)
+	t.Join(err)
+	if len(threads) != 2 || threads[0].UID != 3 || threads[1].UID != 4 ||
+		len(threads[1].Children) != 1 || threads[1].Children[0].UID != 5 {
+		t.Errorf("Threads of the server haven't been parsed correctly")
+	}
+}
+
+func TestServerSortFallback(T *testing.T) {
+	t := mock.Server(T,
+		`S: * PREAUTH [CAPABILITY IMAP4rev1 SORT] Server ready`,
+	)
+	c, err := t.Dial()
+	t.Join(err)
+	registerCommands(c)
+	var mc *MailCon = &MailCon{client: c, mailbox: DFLT_MAILBOX_NAME, delim: "/",
+		mutex: &imapMutex{}, uidStates: make(uidTracker)}
+	// The server fails to sort the mails => the mails are loaded and sorted locally
+	t.Script(
+		`C: A1 SELECT "INBOX"`,
+		`S: * 0 EXISTS`,
+		`S: A1 OK [READ-WRITE] SELECT completed`,
+		`C: A2 UID SORT (SIZE) UTF-8 ALL`,
+		`S: A2 NO Sort failed`,
+		`C: A3 SELECT "INBOX"`,
+		`S: * 0 EXISTS`,
+		`S: A3 OK [READ-WRITE] SELECT completed`,
+		`C: A4 FETCH 1:* (UID FLAGS INTERNALDATE RFC822.SIZE RFC822.HEADER)`,
+		`S: A4 OK Fetch completed`,
+	)
+	mails, err := mc.SortMails("/", SORT_SIZE, false, false)
+	t.Join(err)
+	if 0 != len(mails) {
+		t.Errorf("Expected no mails, but got %d", len(mails))
+	}
+}
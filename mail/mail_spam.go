@@ -0,0 +1,255 @@
+package mail
+
+import (
+	"fmt"
+	"net/textproto"
+	"strconv"
+	"strings"
+)
+
+// Names of the built-in spam providers as used in the configuration (see MailConf.SpamProvider)
+const (
+	SPAM_PROVIDER_GMX          string = "gmx"
+	SPAM_PROVIDER_SPAMASSASSIN string = "spamassassin"
+	SPAM_PROVIDER_RSPAMD       string = "rspamd"
+	SPAM_PROVIDER_MICROSOFT    string = "microsoft"
+)
+
+// The common spam score all providers are normalized to: 0 (no spam) - SPAM_SCORE_MAX. Mails,
+// whose score reaches the threshold of their spam filter, have a score of SPAM_SCORE_THRESHOLD.
+const (
+	SPAM_SCORE_MAX       int = 10
+	SPAM_SCORE_THRESHOLD int = 5
+)
+
+// The classification of a mail by a spam filter
+type SpamResult struct {
+	// The normalized score of the mail (0 - SPAM_SCORE_MAX)
+	Score int
+	// Whether the spam filter classified the mail as spam
+	IsSpam bool
+}
+
+// A spam filter, which classifies mails by adding header fields to them, e.g., SpamAssassin
+type SpamProvider interface {
+	// The name of the provider as used in the configuration, e.g., "spamassassin"
+	Name() string
+	// Reads the classification from the given header and false, if the mail hasn't been
+	// classified by this provider
+	Classify(header textproto.MIMEHeader) (SpamResult, bool)
+}
+
+// All known spam providers: name -> provider (see RegisterSpamProvider)
+var spamProviders map[string]SpamProvider = map[string]SpamProvider{
+	SPAM_PROVIDER_GMX:          gmxSpamProvider{},
+	SPAM_PROVIDER_SPAMASSASSIN: spamAssassinProvider{},
+	SPAM_PROVIDER_RSPAMD:       rspamdProvider{},
+	SPAM_PROVIDER_MICROSOFT:    microsoftSpamProvider{},
+}
+
+// The providers used, if none are configured (in the order of their priority)
+var defaultSpamProviders []string = []string{SPAM_PROVIDER_GMX, SPAM_PROVIDER_SPAMASSASSIN,
+	SPAM_PROVIDER_RSPAMD, SPAM_PROVIDER_MICROSOFT}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////
+///									Public Spam Methods											 ///
+////////////////////////////////////////////////////////////////////////////////////////////////////
+
+/**
+ * Adds the given provider to the known spam providers, so it can be selected in the configuration
+ * by its name. An existing provider with the same name is replaced.
+ * ATTENTION: Has to be called before the first mail connection is created
+ */
+func RegisterSpamProvider(provider SpamProvider) {
+	spamProviders[strings.ToLower(provider.Name())] = provider
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////
+///									Private Spam Methods										 ///
+////////////////////////////////////////////////////////////////////////////////////////////////////
+
+/**
+ * @param names The names of the providers in the order of their priority (empty = default
+ *				providers)
+ * @return The providers for the given names and an error, if a name is unknown
+ */
+func newSpamProviders(names []string) ([]SpamProvider, error) {
+	if 0 == len(names) {
+		names = defaultSpamProviders
+	}
+	var providers []SpamProvider = make([]SpamProvider, 0, len(names))
+	for _, name := range names {
+		provider, ok := spamProviders[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			return nil, fmt.Errorf("Unknown spam provider '%s'", name)
+		}
+		providers = append(providers, provider)
+	}
+	return providers, nil
+}
+
+/**
+ * Classifies the mail with the first of the given providers, which has tagged the header.
+ * @param providers The providers in the order of their priority (nil = default providers)
+ * @return 0 - no spam (or not classified) | >0 - the normalized score of a spam mail, and the name
+ *		   of the provider, that classified the mail (empty, if it hasn't been classified)
+ */
+func parseSpamIndicator(header textproto.MIMEHeader, providers []SpamProvider) (int, string) {
+	if nil == providers {
+		providers, _ = newSpamProviders(nil)
+	}
+	for _, provider := range providers {
+		result, ok := provider.Classify(header)
+		if !ok {
+			continue
+		}
+		// A spam mail always has a positive indicator, even if its score is low
+		if !result.IsSpam {
+			return 0, provider.Name()
+		} else if result.Score < 1 {
+			return 1, provider.Name()
+		}
+		return result.Score, provider.Name()
+	}
+	// By default, a mail that is not tagged with any spam indicating header information is
+	// not a SPAM mail
+	return 0, ""
+}
+
+/**
+ * @return The given score of a spam filter scaled to the common score, so that the threshold of
+ *		   the filter results in SPAM_SCORE_THRESHOLD, e.g., 7.5 of 5.0 -> 8
+ */
+func normalizeSpamScore(score, threshold float64) int {
+	if threshold <= 0 {
+		threshold = float64(SPAM_SCORE_THRESHOLD)
+	}
+	var normalized int = int(score/threshold*float64(SPAM_SCORE_THRESHOLD) + 0.5)
+	if normalized < 0 {
+		return 0
+	} else if normalized > SPAM_SCORE_MAX {
+		return SPAM_SCORE_MAX
+	}
+	return normalized
+}
+
+/**
+ * @return The float values of the given "key=value" pairs, e.g.,
+ *		   "Yes, score=7.3 required=5.0" -> score: 7.3, required: 5.0
+ */
+func spamValues(field string) map[string]float64 {
+	var values map[string]float64 = map[string]float64{}
+	for _, pair := range strings.FieldsFunc(field, func(r rune) bool {
+		return ' ' == r || '\t' == r || ',' == r || ';' == r
+	}) {
+		if parts := strings.SplitN(pair, "=", 2); len(parts) == 2 {
+			if value, err := strconv.ParseFloat(parts[1], 64); err == nil {
+				values[strings.ToLower(parts[0])] = value
+			}
+		}
+	}
+	return values
+}
+
+// GMX: "X-GMX-Antispam: 6 (nemesis text pattern profiler); Detail=V3;"
+type gmxSpamProvider struct{}
+
+func (gmxSpamProvider) Name() string {
+	return SPAM_PROVIDER_GMX
+}
+
+func (gmxSpamProvider) Classify(header textproto.MIMEHeader) (SpamResult, bool) {
+	values, ok := header["X-Gmx-Antispam"]
+	if !ok {
+		return SpamResult{}, false
+	}
+	// GMX already uses a score of 0 (no spam) - 10
+	var score int = parseGMXSpamIndicator(values)
+	if score > SPAM_SCORE_MAX {
+		score = SPAM_SCORE_MAX
+	}
+	return SpamResult{Score: score, IsSpam: score > 0}, true
+}
+
+// SpamAssassin: "X-Spam-Status: Yes, score=7.3 required=5.0 tests=..." or "X-Spam-Score: 7.3"
+type spamAssassinProvider struct{}
+
+func (spamAssassinProvider) Name() string {
+	return SPAM_PROVIDER_SPAMASSASSIN
+}
+
+func (spamAssassinProvider) Classify(header textproto.MIMEHeader) (SpamResult, bool) {
+	if status := header.Get("X-Spam-Status"); len(status) > 0 {
+		var (
+			values map[string]float64 = spamValues(status)
+			score  float64            = values["score"]
+		)
+		// Versions before 3.0 call the score "hits"
+		if _, ok := values["score"]; !ok {
+			score = values["hits"]
+		}
+		return SpamResult{
+			Score:  normalizeSpamScore(score, values["required"]),
+			IsSpam: strings.HasPrefix(strings.ToLower(strings.TrimSpace(status)), "yes"),
+		}, true
+	}
+	if score, err := strconv.ParseFloat(strings.TrimSpace(header.Get("X-Spam-Score")),
+		64); err == nil {
+		return SpamResult{
+			Score: normalizeSpamScore(score, float64(SPAM_SCORE_THRESHOLD)),
+			IsSpam: strings.EqualFold(strings.TrimSpace(header.Get("X-Spam-Flag")), "yes") ||
+				score >= float64(SPAM_SCORE_THRESHOLD),
+		}, true
+	}
+	return SpamResult{}, false
+}
+
+// Rspamd: "X-Spamd-Result: default: True [16.50 / 15.00]; SYMBOL(1.00)[...]; ..."
+type rspamdProvider struct{}
+
+func (rspamdProvider) Name() string {
+	return SPAM_PROVIDER_RSPAMD
+}
+
+func (rspamdProvider) Classify(header textproto.MIMEHeader) (SpamResult, bool) {
+	var result string = header.Get("X-Spamd-Result")
+	start, end := strings.Index(result, "["), strings.Index(result, "]")
+	if start < 0 || end < start {
+		return SpamResult{}, false
+	}
+	var (
+		verdict string   = strings.ToLower(result[:start])
+		scores  []string = strings.Split(result[start+1:end], "/")
+	)
+	score, err := strconv.ParseFloat(strings.TrimSpace(scores[0]), 64)
+	if err != nil {
+		return SpamResult{}, false
+	}
+	var threshold float64
+	if len(scores) > 1 {
+		threshold, _ = strconv.ParseFloat(strings.TrimSpace(scores[1]), 64)
+	}
+	return SpamResult{
+		Score:  normalizeSpamScore(score, threshold),
+		IsSpam: strings.Contains(verdict, "true"),
+	}, true
+}
+
+// Microsoft Exchange: "X-MS-Exchange-Organization-SCL: 5" (Spam Confidence Level -1 - 9)
+type microsoftSpamProvider struct{}
+
+func (microsoftSpamProvider) Name() string {
+	return SPAM_PROVIDER_MICROSOFT
+}
+
+func (microsoftSpamProvider) Classify(header textproto.MIMEHeader) (SpamResult, bool) {
+	scl, err := strconv.Atoi(strings.TrimSpace(header.Get("X-Ms-Exchange-Organization-Scl")))
+	if err != nil {
+		return SpamResult{}, false
+	}
+	// Exchange moves mails with a SCL of 5 or higher into the junk folder by default
+	return SpamResult{
+		Score:  normalizeSpamScore(float64(scl), float64(SPAM_SCORE_THRESHOLD)),
+		IsSpam: scl >= SPAM_SCORE_THRESHOLD,
+	}, true
+}
@@ -0,0 +1,53 @@
+package mail
+
+import (
+	"net/textproto"
+	"testing"
+)
+
+func TestParseSpamProviders(t *testing.T) {
+	for _, test := range []struct {
+		header    textproto.MIMEHeader
+		indicator int
+		provider  string
+	}{
+		{textproto.MIMEHeader{"X-Gmx-Antispam": {"6 (nemesis text pattern profiler); Detail=V3;"}},
+			6, SPAM_PROVIDER_GMX},
+		{textproto.MIMEHeader{"X-Spam-Status": {"Yes, score=7.3 required=5.0 tests=BAYES_99"}},
+			7, SPAM_PROVIDER_SPAMASSASSIN},
+		{textproto.MIMEHeader{"X-Spam-Status": {"No, score=2.1 required=5.0"}}, 0,
+			SPAM_PROVIDER_SPAMASSASSIN},
+		{textproto.MIMEHeader{"X-Spam-Score": {"12.5"}, "X-Spam-Flag": {"YES"}}, 10,
+			SPAM_PROVIDER_SPAMASSASSIN},
+		{textproto.MIMEHeader{"X-Spamd-Result": {"default: True [16.50 / 15.00]; BAYES(5.0)"}},
+			6, SPAM_PROVIDER_RSPAMD},
+		{textproto.MIMEHeader{"X-Ms-Exchange-Organization-Scl": {"-1"}}, 0,
+			SPAM_PROVIDER_MICROSOFT},
+		{textproto.MIMEHeader{"X-Ms-Exchange-Organization-Scl": {"9"}}, 9,
+			SPAM_PROVIDER_MICROSOFT},
+		{textproto.MIMEHeader{"Subject": {"No spam filter"}}, 0, ""},
+	} {
+		if indicator, provider := parseSpamIndicator(test.header, nil); indicator !=
+			test.indicator || provider != test.provider {
+			t.Errorf("Expected %d by '%s' for %v, but got %d by '%s'", test.indicator,
+				test.provider, test.header, indicator, provider)
+		}
+	}
+}
+
+func TestSpamProviderOrder(t *testing.T) {
+	header := textproto.MIMEHeader{"X-Gmx-Antispam": {"0 (Mail was not recognized as spam)"},
+		"X-Ms-Exchange-Organization-Scl": {"6"}}
+	providers, err := newSpamProviders([]string{"Microsoft", SPAM_PROVIDER_GMX})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if indicator, provider := parseSpamIndicator(header, providers); indicator != 6 ||
+		provider != SPAM_PROVIDER_MICROSOFT {
+		t.Errorf("Expected the first configured provider to classify the mail, but got %d by '%s'",
+			indicator, provider)
+	}
+	if _, err := newSpamProviders([]string{"unknown"}); nil == err {
+		t.Errorf("Expected an error for an unknown spam provider")
+	}
+}
@@ -2,6 +2,9 @@ package mail
 
 import (
 	"fmt"
+	"github.com/mxk/go-imap/imap"
+	"github.com/mxk/go-imap/mock"
+	"net/textproto"
 	"reflect"
 	"testing"
 	"time"
@@ -100,12 +103,22 @@ func TestSerializeHeader(t *testing.T) {
 	}
 }
 
+/**
+ * Parses the given multipart body the same way as the content of a fetched mail.
+ */
+func parseMultipartBody(body, boundary string) (Content, error) {
+	mi := &imap.MessageInfo{Attrs: imap.FieldMap{"RFC822.TEXT": body}}
+	content, _, err := parseContent(mi, PMIMEHeader{MimeVersion: 1,
+		ContentType: "multipart/mixed", MultipartBoundary: boundary})
+	return content, err
+}
+
 func TestBase64Parsing(t *testing.T) {
 	var (
 		mailParts Content
 		err       error
 	)
-	if mailParts, err = parseMultipartContent(base64Body,
+	if mailParts, err = parseMultipartBody(base64Body,
 		"--==_mimepart_55e5934148e33_35c43fd168003a1018622b"); err != nil {
 		t.Fatal(err)
 	}
@@ -135,7 +148,7 @@ func TestMultipartParsing(t *testing.T) {
 	} else {
 		//		t.Logf("$$$$$$$$$ Header is: %s", header)
 		var mailParts Content
-		if mailParts, err = parseMultipartContent(multipartMailBody1,
+		if mailParts, err = parseMultipartBody(multipartMailBody1,
 			header.MimeHeader.MultipartBoundary); err != nil {
 			t.Fatalf("Failed parsing multipart content: %s\n", err.Error())
 		}
@@ -227,3 +240,91 @@ func checkMimeHeader(parsed, expected PMIMEHeader, t *testing.T) {
 			parsed.Encoding, expected.Encoding)
 	}
 }
+
+func TestParseMessageIDs(t *testing.T) {
+	header := textproto.MIMEHeader{
+		"Message-Id": []string{"<20130316010526.034C710090703@domain.de>"},
+		"References": []string{"<1@domain.de> (comment)\r\n\t<2@domain.de>", "<3@domain.de>"},
+	}
+	if ids := parseMessageIDs(header, "Message-Id"); len(ids) != 1 ||
+		ids[0] != "<20130316010526.034C710090703@domain.de>" {
+		t.Errorf("Message-ID hasn't been parsed correctly: %v", ids)
+	}
+	if ids := parseMessageIDs(header, "References"); !reflect.DeepEqual(ids,
+		[]string{"<1@domain.de>", "<2@domain.de>", "<3@domain.de>"}) {
+		t.Errorf("References haven't been parsed correctly: %v", ids)
+	}
+	if ids := parseMessageIDs(header, "In-Reply-To"); nil != ids {
+		t.Errorf("Expected no IDs for missing header field, but got %v", ids)
+	}
+}
+
+func TestParseCopyUID(t *testing.T) {
+	// 1) Single UIDs are parsed as numbers
+	uids, ok := parseCopyUID(&imap.Response{Label: "COPYUID",
+		Fields: []imap.Field{"COPYUID", uint32(38505), uint32(304), uint32(3956)}})
+	if !ok || !reflect.DeepEqual(uids, map[uint32]uint32{304: 3956}) {
+		t.Errorf("Single COPYUID hasn't been parsed correctly: %v", uids)
+	}
+	// 2) UID sets are parsed as atoms
+	uids, ok = parseCopyUID(&imap.Response{Label: "COPYUID",
+		Fields: []imap.Field{"COPYUID", uint32(38505), "304,320:319", "3956:3958"}})
+	if !ok || !reflect.DeepEqual(uids, map[uint32]uint32{304: 3956, 319: 3957, 320: 3958}) {
+		t.Errorf("COPYUID sets haven't been parsed correctly: %v", uids)
+	}
+	// Descending ranges are the same as ascending ones
+	uids, ok = parseCopyUID(&imap.Response{Label: "COPYUID",
+		Fields: []imap.Field{"COPYUID", uint32(38505), "319:320", "3958:3957"}})
+	if !ok || !reflect.DeepEqual(uids, map[uint32]uint32{319: 3957, 320: 3958}) {
+		t.Errorf("Descending COPYUID range hasn't been parsed correctly: %v", uids)
+	}
+	// 3) Other responses and sets of different sizes are ignored
+	if _, ok = parseCopyUID(&imap.Response{Label: "APPENDUID",
+		Fields: []imap.Field{"APPENDUID", uint32(38505), uint32(3955)}}); ok {
+		t.Error("APPENDUID has been parsed as COPYUID")
+	}
+	if _, ok = parseCopyUID(&imap.Response{Label: "COPYUID",
+		Fields: []imap.Field{"COPYUID", uint32(38505), "304:305", uint32(3956)}}); ok {
+		t.Error("COPYUID with different number of UIDs has been accepted")
+	}
+	if _, ok = parseCopyUID(&imap.Response{Label: "COPYUID",
+		Fields: []imap.Field{"COPYUID", uint32(38505), "2:0", "3956:3958"}}); ok {
+		t.Error("COPYUID with invalid UID 0 has been accepted")
+	}
+}
+
+func TestMoveMailCommand(T *testing.T) {
+	t := mock.Server(T,
+		`S: * PREAUTH [CAPABILITY IMAP4rev1 MOVE UIDPLUS] Server ready`,
+	)
+	c, err := t.Dial()
+	t.Join(err)
+	registerCommands(c)
+	var mc *MailCon = &MailCon{client: c, mailbox: DFLT_MAILBOX_NAME, delim: "/",
+		uidStates: make(uidTracker)}
+	var listener chan MailEvent = mc.AddEventListener()
+	// The moved mail is reported by the EXPUNGE response, its new UID by the untagged COPYUID
+	t.Script(
+		`C: A1 SELECT "INBOX"`,
+		`S: * 3 EXISTS`,
+		`S: * OK [UIDVALIDITY 38505] UIDs valid`,
+		`S: A1 OK [READ-WRITE] SELECT completed`,
+		`C: A2 UID MOVE 42 "INBOX/Archive"`,
+		`S: * OK [COPYUID 38506 42 3956] Moved`,
+		`S: * 2 EXPUNGE`,
+		`S: A2 OK Move completed`,
+	)
+	newUID, err := mc.moveMail_internal("42", "/", "Archive")
+	t.Join(err)
+	if 3956 != newUID {
+		t.Errorf("Expected new UID 3956, but got %d", newUID)
+	}
+	select {
+	case event := <-listener:
+		if EXPUNGE_EVENT != event.Type {
+			t.Errorf("Expected an expunge event, but got %s", event.Type)
+		}
+	default:
+		t.Error("The EXPUNGE response of the move command hasn't been published")
+	}
+}
@@ -0,0 +1,355 @@
+package mail
+
+import (
+	"fmt"
+	"github.com/mxk/go-imap/imap"
+	"sort"
+	"strings"
+	"time"
+)
+
+// A discussion, whose mails can be spread over several folders (e.g., INBOX and Sent)
+type Conversation struct {
+	// The base subject of the conversation (without "Re:", "Fwd:", ...)
+	Subject string
+	// The thread tree of the conversation (the root node might be a missing mail)
+	Thread *ThreadNode
+	// All mails of the conversation without their content, oldest first
+	Mails []*Mail
+	// All folders, that contain mails of the conversation
+	Folders []string
+	// The date of the newest mail of the conversation
+	LastDate time.Time
+}
+
+// Used to sort conversations by the date of their newest mail (newest first)
+type ConversationSlice []Conversation
+
+func (p ConversationSlice) Len() int           { return len(p) }
+func (p ConversationSlice) Less(i, j int) bool { return p[i].LastDate.After(p[j].LastDate) }
+func (p ConversationSlice) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+
+// Used to sort thread nodes by the date of their (first) mail (oldest first)
+type threadSlice []*ThreadNode
+
+func (p threadSlice) Len() int           { return len(p) }
+func (p threadSlice) Less(i, j int) bool { return p[i].date().Before(p[j].date()) }
+func (p threadSlice) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+
+// Used to sort the mails of a conversation by their date (oldest first)
+type mailPtrSlice []*Mail
+
+func (p mailPtrSlice) Len() int           { return len(p) }
+func (p mailPtrSlice) Less(i, j int) bool { return p[i].Header.Date.Before(p[j].Header.Date) }
+func (p mailPtrSlice) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+
+// A node of the JWZ threading algorithm (see http://www.jwz.org/doc/threading.html)
+type container struct {
+	// The Message-ID of the mail of this container
+	id string
+	// The mail of this container (nil = the mail is only known from references)
+	mail     *Mail
+	parent   *container
+	children []*container
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////
+///									Public Thread Methods										 ///
+////////////////////////////////////////////////////////////////////////////////////////////////////
+
+/**
+ * Groups all mails of the given folders into conversations by their Message-ID, In-Reply-To and
+ * References headers. Replies stored in different folders (e.g., the own replies in the Sent
+ * folder) thereby end up in the same conversation.
+ * @param folders The folders to build the conversations for ("/" = root)
+ * @return All conversations, ordered by the date of their newest mail (newest first)
+ */
+func (mc *MailCon) LoadConversations(folders []string) ([]Conversation, error) {
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+	var mails []Mail = []Mail{}
+	for _, folder := range folders {
+		set, _ := imap.NewSeqSet("1:*")
+		folderMails, err := mc.loadMails(set, folder, false, mc.client.Fetch)
+		if err != nil {
+			return []Conversation{}, err
+		}
+		mails = append(mails, folderMails...)
+	}
+	var conversations []Conversation = []Conversation{}
+	for _, thread := range buildThreads(mails) {
+		conversations = append(conversations, newConversation(thread))
+	}
+	sort.Sort(ConversationSlice(conversations))
+	return conversations, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////
+///									Private Thread Methods										 ///
+////////////////////////////////////////////////////////////////////////////////////////////////////
+
+/**
+ * Builds the thread trees of the given mails with the JWZ threading algorithm:
+ *	1) Link the containers of all referenced mails to parent -> child chains
+ *	2) Remove containers of missing mails, which don't group multiple replies
+ *	3) Merge threads with the same base subject
+ * @return The root nodes of all threads ordered by the date of their first mail (oldest first)
+ */
+func buildThreads(mails []Mail) []*ThreadNode {
+	var (
+		byID  map[string]*container = make(map[string]*container)
+		all   []*container          = []*container{}
+		roots []*container          = []*container{}
+	)
+	// Creates the container for the given message ID, if it doesn't exist yet
+	getContainer := func(id string) *container {
+		if _, ok := byID[id]; !ok {
+			byID[id] = &container{id: id}
+			all = append(all, byID[id])
+		}
+		return byID[id]
+	}
+	// Process the mails by date to merge threads deterministically (oldest mail first)
+	sortMails(mails, sortCriteria[SORT_DATE], false)
+	// 1) Create a container for each mail and link it to all of its references
+	for i := range mails {
+		var (
+			curMail *Mail  = &mails[i]
+			id      string = curMail.Header.MessageID
+		)
+		if known, ok := byID[id]; 0 == len(id) || (ok && nil != known.mail) {
+			// Mails without ID or stored in multiple folders (e.g., sent to oneself) => keep both
+			id = fmt.Sprintf("<%d.%s@watney>", curMail.UID, curMail.Header.Folder)
+		}
+		cur := getContainer(id)
+		cur.mail = curMail
+		var (
+			refs   []string = append([]string{}, curMail.Header.References...)
+			parent *container
+		)
+		if len(curMail.Header.InReplyTo) > 0 &&
+			(0 == len(refs) || refs[len(refs)-1] != curMail.Header.InReplyTo) {
+			refs = append(refs, curMail.Header.InReplyTo)
+		}
+		for _, ref := range refs {
+			refContainer := getContainer(ref)
+			if nil != parent && nil == refContainer.parent && !parent.hasAncestor(refContainer) {
+				parent.addChild(refContainer)
+			}
+			parent = refContainer
+		}
+		// The references of the mail itself take precedence over the ones of other mails
+		if nil != parent && !parent.hasAncestor(cur) {
+			cur.unlink()
+			parent.addChild(cur)
+		}
+	}
+	for _, c := range all {
+		if nil == c.parent {
+			roots = append(roots, c)
+		}
+	}
+	// 2) Remove all containers of missing mails, that only group one reply
+	roots = pruneContainers(roots, true)
+	// 3) Merge threads, that have been broken up (e.g., by clients not setting References)
+	roots = mergeBySubject(roots)
+	var threads []*ThreadNode = make([]*ThreadNode, 0, len(roots))
+	for _, root := range roots {
+		threads = append(threads, root.toThreadNode())
+	}
+	sort.Sort(threadSlice(threads))
+	return threads
+}
+
+/**
+ * Removes containers of missing mails: their children are promoted to their parent, unless the
+ * container is a root with multiple children (which groups several replies to a missing mail).
+ */
+func pruneContainers(containers []*container, isRoot bool) []*container {
+	var pruned []*container = []*container{}
+	for _, c := range containers {
+		c.children = pruneContainers(c.children, false)
+		if nil != c.mail || (isRoot && len(c.children) > 1) {
+			pruned = append(pruned, c)
+			continue
+		}
+		for _, child := range c.children {
+			child.parent = c.parent
+			pruned = append(pruned, child)
+		}
+	}
+	return pruned
+}
+
+/**
+ * Merges root containers with the same base subject: a reply becomes the child of the original
+ * mail, all other mails are grouped under a container of a missing mail.
+ */
+func mergeBySubject(roots []*container) []*container {
+	var (
+		merged    []*container          = []*container{}
+		bySubject map[string]*container = make(map[string]*container)
+	)
+	for _, c := range roots {
+		subject := c.baseSubject()
+		other, ok := bySubject[subject]
+		if !ok || 0 == len(subject) {
+			bySubject[subject] = c
+			merged = append(merged, c)
+			continue
+		}
+		switch {
+		case nil == other.mail && nil == c.mail:
+			for _, child := range c.children {
+				other.addChild(child)
+			}
+		case nil == other.mail:
+			other.addChild(c)
+		case nil == c.mail:
+			c.addChild(other)
+			replaceContainer(merged, other, c)
+			bySubject[subject] = c
+		case c.isReply() && !other.isReply():
+			other.addChild(c)
+		case other.isReply() && !c.isReply():
+			c.addChild(other)
+			replaceContainer(merged, other, c)
+			bySubject[subject] = c
+		default:
+			group := &container{}
+			replaceContainer(merged, other, group)
+			group.addChild(other)
+			group.addChild(c)
+			bySubject[subject] = group
+		}
+	}
+	return merged
+}
+
+func replaceContainer(containers []*container, old, new *container) {
+	for i := range containers {
+		if containers[i] == old {
+			containers[i] = new
+		}
+	}
+}
+
+func (c *container) addChild(child *container) {
+	child.parent = c
+	c.children = append(c.children, child)
+}
+
+/**
+ * Removes this container from the children of its parent.
+ */
+func (c *container) unlink() {
+	if nil == c.parent {
+		return
+	}
+	for i, child := range c.parent.children {
+		if child == c {
+			c.parent.children = append(c.parent.children[:i], c.parent.children[i+1:]...)
+			break
+		}
+	}
+	c.parent = nil
+}
+
+/**
+ * @return Whether the given container is this container or one of its ancestors
+ */
+func (c *container) hasAncestor(ancestor *container) bool {
+	for cur := c; nil != cur; cur = cur.parent {
+		if cur == ancestor {
+			return true
+		}
+	}
+	return false
+}
+
+/**
+ * @return The base subject of the mail of this container or of its first child
+ */
+func (c *container) baseSubject() string {
+	if nil != c.mail {
+		return baseSubject(c.mail.Header.Subject)
+	}
+	if len(c.children) > 0 {
+		return c.children[0].baseSubject()
+	}
+	return ""
+}
+
+/**
+ * @return Whether the subject of the mail of this container has a reply or forward prefix
+ */
+func (c *container) isReply() bool {
+	return nil != c.mail && baseSubject(c.mail.Header.Subject) !=
+		strings.ToLower(strings.Join(strings.Fields(c.mail.Header.Subject), " "))
+}
+
+/**
+ * Converts this container and all its children into thread nodes (children sorted by date).
+ */
+func (c *container) toThreadNode() *ThreadNode {
+	var node *ThreadNode = &ThreadNode{MessageID: c.id, Mail: c.mail}
+	if nil != c.mail {
+		node.UID = c.mail.UID
+	}
+	for _, child := range c.children {
+		node.Children = append(node.Children, child.toThreadNode())
+	}
+	sort.Sort(threadSlice(node.Children))
+	return node
+}
+
+/**
+ * Creates the conversation of the given thread.
+ */
+func newConversation(thread *ThreadNode) Conversation {
+	var (
+		conversation Conversation    = Conversation{Thread: thread, Mails: []*Mail{}}
+		folders      map[string]bool = make(map[string]bool)
+	)
+	thread.walk(func(node *ThreadNode) {
+		if nil == node.Mail {
+			return
+		}
+		if 0 == len(conversation.Subject) {
+			conversation.Subject = baseSubject(node.Mail.Header.Subject)
+		}
+		conversation.Mails = append(conversation.Mails, node.Mail)
+		if !folders[node.Mail.Header.Folder] {
+			folders[node.Mail.Header.Folder] = true
+			conversation.Folders = append(conversation.Folders, node.Mail.Header.Folder)
+		}
+		if node.Mail.Header.Date.After(conversation.LastDate) {
+			conversation.LastDate = node.Mail.Header.Date
+		}
+	})
+	sort.Sort(mailPtrSlice(conversation.Mails))
+	return conversation
+}
+
+/**
+ * Calls the given function for this node and all of its descendants (depth-first).
+ */
+func (t *ThreadNode) walk(visit func(node *ThreadNode)) {
+	visit(t)
+	for _, child := range t.Children {
+		child.walk(visit)
+	}
+}
+
+/**
+ * @return The date of the mail of this node or of its first child, if the mail is missing
+ */
+func (t *ThreadNode) date() time.Time {
+	if nil != t.Mail {
+		return t.Mail.Header.Date
+	}
+	if len(t.Children) > 0 {
+		return t.Children[0].date()
+	}
+	return time.Time{}
+}
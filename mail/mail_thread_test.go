@@ -0,0 +1,49 @@
+package mail
+
+import (
+	"testing"
+	"time"
+)
+
+func threadTestMail(uid uint32, folder, subject, id, inReplyTo string, refs []string,
+	date time.Time) Mail {
+	return Mail{UID: uid, Header: &Header{Folder: folder, Subject: subject, MessageID: id,
+		InReplyTo: inReplyTo, References: refs, Date: date}}
+}
+
+func TestBuildThreads(t *testing.T) {
+	now := time.Now()
+	mails := []Mail{
+		// Reply stored in Sent, which references the mail in the INBOX
+		threadTestMail(7, "Sent", "Re: Potatoes", "<2@mars>", "<1@mars>", []string{"<1@mars>"},
+			now.Add(-2*time.Hour)),
+		threadTestMail(3, "/", "Potatoes", "<1@mars>", "", nil, now.Add(-3*time.Hour)),
+		// Reply to the reply, which only sets References
+		threadTestMail(4, "/", "Re: Re: Potatoes", "<3@mars>", "",
+			[]string{"<1@mars>", "<2@mars>"}, now.Add(-time.Hour)),
+		// Two replies to a mail, that isn't available (anymore)
+		threadTestMail(5, "/", "Re: Water", "<5@mars>", "<4@mars>", nil, now.Add(-time.Minute)),
+		threadTestMail(6, "/", "Re: Water", "<6@mars>", "<4@mars>", nil, now),
+		// Reply without any references
+		threadTestMail(8, "/", "Re: Potatoes", "", "", nil, now),
+	}
+	threads := buildThreads(mails)
+	if len(threads) != 2 {
+		t.Fatalf("Expected 2 threads, but got %d", len(threads))
+	}
+	potatoes := threads[0]
+	if potatoes.UID != 3 || len(potatoes.Children) != 2 || potatoes.Children[0].UID != 7 ||
+		potatoes.Children[1].UID != 8 || potatoes.Children[0].Children[0].UID != 4 {
+		t.Errorf("Potatoes thread hasn't been built correctly")
+	}
+	water := threads[1]
+	if water.Mail != nil || water.MessageID != "<4@mars>" || len(water.Children) != 2 {
+		t.Errorf("Expected missing mail <4@mars> with 2 replies as root of the water thread")
+	}
+	conversation := newConversation(potatoes)
+	if conversation.Subject != "potatoes" || len(conversation.Mails) != 4 ||
+		len(conversation.Folders) != 2 || !conversation.LastDate.Equal(now) ||
+		conversation.Mails[0].UID != 3 {
+		t.Errorf("Conversation hasn't been created correctly: %v", conversation)
+	}
+}
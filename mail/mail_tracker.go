@@ -0,0 +1,213 @@
+package mail
+
+import (
+	"fmt"
+	"github.com/mxk/go-imap/imap"
+)
+
+// The state of a folder at the time it was last checked for new mails
+type FolderState struct {
+	// The UIDVALIDITY of the folder (all known UIDs are invalid, once this value changes)
+	UIDValidity uint32
+	// The UID that will be assigned to the next mail arriving in the folder (UIDNEXT)
+	UIDNext uint32
+}
+
+// The counters of a folder as returned by the STATUS command
+type FolderStatus struct {
+	// The folder path ("/" = root)
+	Folder string
+	// The number of mails in the folder
+	Messages uint32
+	// The number of mails without the \Seen flag
+	Unseen uint32
+	// The UID that will be assigned to the next mail arriving in the folder
+	UIDNext uint32
+	// The UIDVALIDITY of the folder
+	UIDValidity uint32
+	// Whether mails have arrived since the folder was last checked (see loadNewMails_internal)
+	HasNewMails bool
+}
+
+// Tracks the state of each folder to detect new mails by their UID: folder path -> FolderState
+type uidTracker map[string]FolderState
+
+/**
+ * Starts tracking the given folder with the given state, if the folder isn't tracked yet or if
+ * its UIDVALIDITY has changed (in which case all previously tracked UIDs are invalid).
+ */
+func (t uidTracker) track(folder string, state FolderState) {
+	if known, ok := t[folder]; !ok || known.UIDValidity != state.UIDValidity {
+		t[folder] = state
+	}
+}
+
+/**
+ * Marks all mails with the given UIDs as known for the given folder.
+ */
+func (t uidTracker) advance(folder string, uids []uint32) {
+	state := t[folder]
+	for _, uid := range uids {
+		if uid >= state.UIDNext {
+			state.UIDNext = uid + 1
+		}
+	}
+	t[folder] = state
+}
+
+/**
+ * @return Whether the given folder contains mails, that haven't been seen yet according to the
+ *		   given state. Unknown folders are tracked from now on.
+ */
+func (t uidTracker) hasNew(folder string, state FolderState) bool {
+	known, ok := t[folder]
+	if !ok || known.UIDValidity != state.UIDValidity {
+		t.track(folder, state)
+		return false
+	}
+	return state.UIDNext > known.UIDNext
+}
+
+/**
+ * @return Whether the mail with the given UID hasn't been seen in the given folder yet.
+ */
+func (t uidTracker) isNew(folder string, uid uint32) bool {
+	return uid >= t[folder].UIDNext
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////
+///									Public Tracker Methods										 ///
+////////////////////////////////////////////////////////////////////////////////////////////////////
+
+/**
+ * Retrieves the counters of the given folder with the STATUS command (without selecting it).
+ */
+func (mc *MailCon) FolderStatus(folder string) (FolderStatus, error) {
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+	return mc.folderStatus_internal(folder)
+}
+
+/**
+ * Retrieves the counters of all folders the user subscribed to, e.g., to show the number of unseen
+ * mails for each folder.
+ */
+func (mc *MailCon) SubscribedFolderStatus() ([]FolderStatus, error) {
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+	var (
+		folders  []*Folder
+		statuses []FolderStatus = []FolderStatus{}
+		err      error
+	)
+	if folders, err = mc.listFolders_internal(); err != nil {
+		return statuses, err
+	}
+	for _, folder := range folders {
+		if !folder.Subscribed || !folder.Selectable {
+			continue
+		}
+		if status, err := mc.folderStatus_internal(folder.Path); err != nil {
+			return statuses, err
+		} else {
+			statuses = append(statuses, status)
+		}
+	}
+	return statuses, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////
+///									Private Tracker Methods										 ///
+////////////////////////////////////////////////////////////////////////////////////////////////////
+
+/**
+ * Loads all mails, that have arrived in the given folder since the last call of this method. The
+ * new mails are published to all event listeners (see publishNewMails), since the mails are only
+ * returned once. The first call for a folder (or the first call after its UIDVALIDITY has
+ * changed), only starts tracking the folder and returns no mails. Loading the mails of a folder
+ * (e.g., with LoadAllMailOverviewsFromFolder) starts tracking the folder as well.
+ * New mails are detected by their UIDs ("UID <UIDNEXT>:*"), which keeps the result exact, even if
+ * mails have been expunged or other clients are connected to the mailbox at the same time.
+ * @param folder The folder to check for new mails ("/" = root)
+ * @return All new mails including their content
+ * ATTENTION: DOES NOT LOCK THE IMAP CONNECTION! => Has to be wrapped into a mutex lock method
+ */
+func (mc *MailCon) loadNewMails_internal(folder string) ([]Mail, error) {
+	if 0 == len(folder) {
+		folder = "/"
+	}
+	// 1) Select the folder to retrieve its current UIDVALIDITY and UIDNEXT
+	if err := mc.selectFolder(folder, true); err != nil {
+		return []Mail{}, err
+	}
+	if nil == mc.client.Mailbox {
+		return []Mail{}, fmt.Errorf("No status available for folder '%s'", folder)
+	}
+	var (
+		known FolderState = mc.uidStates[folder]
+		cur   FolderState = FolderState{
+			UIDValidity: mc.client.Mailbox.UIDValidity,
+			UIDNext:     mc.client.Mailbox.UIDNext,
+		}
+		mails []Mail
+		err   error
+	)
+	// 2) Check whether new mails can have arrived at all
+	if known.UIDValidity != cur.UIDValidity || (cur.UIDNext > 0 && cur.UIDNext <= known.UIDNext) {
+		mc.uidStates.track(folder, cur)
+		return []Mail{}, nil
+	}
+	// 3) Fetch all mails with a UID of at least the last known UIDNEXT
+	//	  ATTENTION: "n:*" always contains the mail with the highest UID, even if its UID is < n
+	if known.UIDNext < 1 {
+		known.UIDNext = 1
+	}
+	set, _ := imap.NewSeqSet(fmt.Sprintf("%d:*", known.UIDNext))
+	if mails, err = mc.loadMails(set, folder, true, mc.client.UIDFetch); err != nil {
+		return []Mail{}, err
+	}
+	var (
+		newMails []Mail   = []Mail{}
+		newUIDs  []uint32 = []uint32{}
+	)
+	for _, curMail := range mails {
+		if mc.uidStates.isNew(folder, curMail.UID) {
+			newMails = append(newMails, curMail)
+			newUIDs = append(newUIDs, curMail.UID)
+		}
+	}
+	// 4) Only remember the new mails, once they have been loaded successfully
+	mc.uidStates.advance(folder, newUIDs)
+	return newMails, nil
+}
+
+/**
+ * ATTENTION: DOES NOT LOCK THE IMAP CONNECTION! => Has to be wrapped into a mutex lock method
+ */
+func (mc *MailCon) folderStatus_internal(folder string) (FolderStatus, error) {
+	if 0 == len(folder) {
+		folder = "/"
+	}
+	var (
+		cmd    *imap.Command
+		status FolderStatus = FolderStatus{Folder: folder}
+		err    error
+	)
+	if cmd, err = mc.waitFor(mc.client.Status(mc.mailboxName(folder), "MESSAGES", "UNSEEN",
+		"UIDNEXT", "UIDVALIDITY")); err != nil {
+		return status, err
+	}
+	for _, resp := range cmd.Data {
+		if mboxStatus := resp.MailboxStatus(); nil != mboxStatus {
+			status.Messages = mboxStatus.Messages
+			status.Unseen = mboxStatus.Unseen
+			status.UIDNext = mboxStatus.UIDNext
+			status.UIDValidity = mboxStatus.UIDValidity
+		}
+	}
+	status.HasNewMails = mc.uidStates.hasNew(folder, FolderState{
+		UIDValidity: status.UIDValidity,
+		UIDNext:     status.UIDNext,
+	})
+	return status, nil
+}
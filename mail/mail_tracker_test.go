@@ -0,0 +1,42 @@
+package mail
+
+import (
+	"testing"
+)
+
+func TestUIDTracker(t *testing.T) {
+	var tracker uidTracker = make(uidTracker)
+	// 1) Start tracking a folder, re-tracking with the same UIDVALIDITY keeps the known state
+	tracker.track("/", FolderState{UIDValidity: 42, UIDNext: 100})
+	tracker.track("/", FolderState{UIDValidity: 42, UIDNext: 105})
+	if state := tracker["/"]; state.UIDNext != 100 {
+		t.Fatalf("Expected UIDNEXT to remain 100, but got %d", state.UIDNext)
+	}
+	// 2) Only mails with a UID >= UIDNEXT are new
+	if tracker.isNew("/", 99) || !tracker.isNew("/", 100) {
+		t.Fatal("Expected only UIDs >= 100 to be new")
+	}
+	tracker.advance("/", []uint32{100, 103})
+	if state := tracker["/"]; state.UIDNext != 104 {
+		t.Fatalf("Expected UIDNEXT to be advanced to 104, but got %d", state.UIDNext)
+	}
+	// 3) A changed UIDVALIDITY invalidates the known state
+	tracker.track("/", FolderState{UIDValidity: 43, UIDNext: 7})
+	if state := tracker["/"]; state.UIDValidity != 43 || state.UIDNext != 7 {
+		t.Fatalf("Expected the state to be reset after UIDVALIDITY change, but got %v", state)
+	}
+}
+
+func TestUIDTrackerHasNew(t *testing.T) {
+	var tracker uidTracker = make(uidTracker)
+	// 1) Unknown folders have no new mails, but are tracked from now on
+	if tracker.hasNew("Lists", FolderState{UIDValidity: 1, UIDNext: 10}) {
+		t.Fatal("Expected an untracked folder to have no new mails")
+	}
+	if tracker.hasNew("Lists", FolderState{UIDValidity: 1, UIDNext: 10}) {
+		t.Fatal("Expected an unchanged folder to have no new mails")
+	}
+	if !tracker.hasNew("Lists", FolderState{UIDValidity: 1, UIDNext: 12}) {
+		t.Fatal("Expected the folder to have new mails after UIDNEXT increased")
+	}
+}
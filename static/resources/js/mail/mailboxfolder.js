@@ -731,26 +731,9 @@ wat.mail.Inbox.prototype.synchFolder = function(reregisterCb) {
     var self = this;
     wat.xhr.send(wat.mail.CHECK_MAILS_URI, function (event) {
         // request complete
-        var req = event.currentTarget,
-            mailsJSON,
-            mails;
+        var req = event.currentTarget;
         if (req.isSuccess()) {
-            mailsJSON = req.getResponseJson();
-            mails = goog.array.map(mailsJSON, function(curMailJSON) {
-                var newMail = new wat.mail.MailItem(curMailJSON, curMailJSON.Header.Folder);
-                newMail.renderMail(function(mail) {
-                    // 1) Unhighlight currently active mail
-                    self.lastActiveMailItem_.highlightOverviewItem(false);
-                    // 2) Activate the clicked mail
-                    self.showMail(mail);
-                }, true);
-                return newMail;
-            });
-            mails = self.postProcessMails_(mails);
-            if (mails.length > 0) {
-                self.addMailsToFolder(mails);
-                wat.app.mailHandler.notifyAboutMails(mails.length, self.Name);
-            }
+            self.addNewMails(req.getResponseJson());
             if (goog.isDefAndNotNull(reregisterCb)) reregisterCb.call(wat.app.mailHandler);
         } else {
             // There could be multiple error possibilities here:
@@ -774,6 +757,31 @@ wat.mail.Inbox.prototype.synchFolder = function(reregisterCb) {
 
 };
 
+/**
+ * Adds the given new mails (either polled or pushed by the backend) to the inbox and notifies the
+ * user about them.
+ * @param {Array} mailsJSON The new mails as returned by the backend
+ * @public
+ */
+wat.mail.Inbox.prototype.addNewMails = function(mailsJSON) {
+    var self = this,
+        mails = goog.array.map(mailsJSON, function(curMailJSON) {
+            var newMail = new wat.mail.MailItem(curMailJSON, curMailJSON.Header.Folder);
+            newMail.renderMail(function(mail) {
+                // 1) Unhighlight currently active mail
+                self.lastActiveMailItem_.highlightOverviewItem(false);
+                // 2) Activate the clicked mail
+                self.showMail(mail);
+            }, true);
+            return newMail;
+        });
+    mails = self.postProcessMails_(mails);
+    if (mails.length > 0) {
+        self.addMailsToFolder(mails);
+        wat.app.mailHandler.notifyAboutMails(mails.length, self.Name);
+    }
+};
+
 /**
  * @override
  * @returns {Array}
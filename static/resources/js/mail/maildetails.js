@@ -27,6 +27,7 @@ wat.mail.MailDetails.prototype.CONTENT_DOMID_ = "mailDetails_Content";
 
 wat.mail.MailDetails.prototype.IMG_LOADING_NOTIFICATION_DOMID_ = "imgWarning_Content";
 wat.mail.MailDetails.prototype.IMG_LOAD_BTN_DOMID_ = "imgWarning_Load_Btn";
+wat.mail.MailDetails.prototype.IMG_TRUST_BTN_DOMID_ = "imgWarning_Trust_Btn";
 wat.mail.MailDetails.prototype.IMG_CANCEL_BTN_DOMID_ = "imgWarning_Cancel_Btn";
 /**
  * Image warning notification for the user, to decide, whether to allow loading of external
@@ -129,28 +130,13 @@ wat.mail.MailDetails.prototype.createHtmlFragment_ = function(mailItem, opt_with
         });
     // 1) Remove style nodes from mail since they can destroy the look & feel
     goog.array.forEach(d_styleNodes, function(curNode) { goog.dom.removeNode(curNode); });
-    // 2) Replace all img 'src' tags to avoid default loading
-    if (!goog.isDefAndNotNull(opt_withImg) || (goog.isDefAndNotNull(opt_withImg) && !opt_withImg)) {
-        // 2a) Find and replace src attribute of img tags
-        self.filterImages_(d_htmlContent);
-        // 2b) Add notification for user
+    // 2) Remote images have already been removed by the server => notify the user
+    if (mail.Content.get("text/html").RemoteContentBlocked && !opt_withImg) {
         self.addAndShowImgWarning_(mailItem);
     }
     return d_htmlContent;
 };
 
-/**
- *
- * @param d_htmlContent
- * @private
- */
-wat.mail.MailDetails.prototype.filterImages_ = function(d_htmlContent) {
-    var d_imgNodes = goog.dom.getElementsByTagNameAndClass("img", "", d_htmlContent);
-    goog.array.forEach(d_imgNodes, function(curImg) {
-        curImg.src = "";
-    });
-};
-
 /**
  * Adds the notification message at the top of the mail content section to notify the user about
  * potential harmful images.
@@ -162,6 +148,7 @@ wat.mail.MailDetails.prototype.addAndShowImgWarning_ = function(curMail) {
         d_imgNotification = goog.dom.getElement(self.IMG_LOADING_NOTIFICATION_DOMID_),
         d_zippyMsg = goog.soy.renderAsElement(wat.soy.mail.loadMailImg, {
             BtnLoadID: self.IMG_LOAD_BTN_DOMID_,
+            BtnTrustID: self.IMG_TRUST_BTN_DOMID_,
             BtnCancelID: self.IMG_CANCEL_BTN_DOMID_
         });
     self.imgWarning_ = new goog.ui.Zippy("", d_imgNotification, true);
@@ -170,9 +157,20 @@ wat.mail.MailDetails.prototype.addAndShowImgWarning_ = function(curMail) {
         function () {
             // 1) Remember decision for next loading
             curMail.Mail.LoadContentImages = true;
-            // 2) Clean details view and re-render mail now with loaded images
-            self.clean.call(self);
-            self.render.call(self, curMail, true);
+            // 2) Reload the content with remote images and re-render the mail
+            curMail.loadContent(function() {
+                self.clean.call(self);
+                self.render.call(self, curMail, true);
+            }, true);
+        }, false);
+    goog.events.listen(goog.dom.getElement(self.IMG_TRUST_BTN_DOMID_), goog.events.EventType.CLICK,
+        function () {
+            curMail.Mail.LoadContentImages = true;
+            // Remote images of all mails of this sender are loaded from now on
+            curMail.loadContent(function() {
+                self.clean.call(self);
+                self.render.call(self, curMail, true);
+            }, true, true);
         }, false);
     goog.events.listen(goog.dom.getElement(self.IMG_CANCEL_BTN_DOMID_), goog.events.EventType.CLICK,
         function () {
@@ -106,6 +106,12 @@ wat.mail.MailHandler.prototype.mailboxFolders_ = new goog.structs.Map();
  */
 wat.mail.MailHandler.prototype.pollTimer_;
 
+/**
+ * The stream of mail events pushed by the backend (see registerUpdateEvents)
+ * @type {EventSource}
+ */
+wat.mail.MailHandler.prototype.eventSource_ = null;
+
 /**
  * Number of mails that the user should be notified about.
  * @type {int}
@@ -154,7 +160,9 @@ wat.mail.MailHandler.prototype.switchMailboxFolder = function(mailboxFolder) {
 };
 
 /**
- * This method registers a timer to continuously poll the backend for new messages.
+ * This method registers for new mails, which are pushed by the backend (Server-Sent Events). In
+ * browsers without Server-Sent Events, a timer is registered to continuously poll the backend for
+ * new messages instead.
  * @param {boolean} [opt_enable] True|undefined - Registers all update events to check for new
  *          mails.
  *          False - Unregisters all update events.
@@ -162,7 +170,27 @@ wat.mail.MailHandler.prototype.switchMailboxFolder = function(mailboxFolder) {
 wat.mail.MailHandler.prototype.registerUpdateEvents = function(opt_enable) {
     var self = this,
         inbox = self.mailboxFolders_.get(wat.mail.MailboxFolder.INBOX);
-    if (!goog.isDefAndNotNull(opt_enable) || opt_enable) {
+    if (goog.isDefAndNotNull(opt_enable) && !opt_enable) {
+        if (goog.isDefAndNotNull(self.eventSource_)) {
+            self.eventSource_.close();
+            self.eventSource_ = null;
+        }
+        return;
+    }
+    if (goog.isDef(window.EventSource)) {
+        if (goog.isDefAndNotNull(self.eventSource_)) return;
+        self.eventSource_ = new EventSource(wat.mail.MAIL_EVENTS_URI);
+        self.eventSource_.addEventListener("newMail", function(event) {
+            var mailEvent = goog.json.parse(event.data);
+            // Only the inbox is watched by the backend
+            if (mailEvent.Folder === "/") inbox.addNewMails(mailEvent.Mails);
+        }, false);
+        self.eventSource_.onerror = function() {
+            // The backend refused the event stream (e.g., the session has timed out) => a single
+            // poll handles the error
+            if (self.eventSource_.readyState === EventSource.CLOSED) inbox.synchFolder();
+        };
+    } else {
         goog.Timer.callOnce(function() {
             //console.log("### Starting poll for new mails");
             inbox.synchFolder(self.registerUpdateEvents);
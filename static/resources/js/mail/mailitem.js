@@ -98,13 +98,20 @@ wat.mail.MailItem.prototype.renderMail = function(activateClickEventCb, opt_prep
 };
 
 /**
+ * @param {function(wat.mail.MailItem)} [successLoadCb]
+ * @param {boolean} [opt_allowRemote] True - Remote images of this mail are loaded
+ * @param {boolean} [opt_trustSender] True - Remote images of all mails of the sender are loaded
+ *                                     (until the session ends)
  * @public
  */
-wat.mail.MailItem.prototype.loadContent = function(successLoadCb) {
+wat.mail.MailItem.prototype.loadContent = function(successLoadCb, opt_allowRemote,
+                                                   opt_trustSender) {
     var self = this,
         data = new goog.Uri.QueryData();
     data.add("uid", self.Mail.UID);
     data.add("folder", self.Mail.Header.Folder);
+    if (opt_allowRemote) data.add("allowRemote", "true");
+    if (opt_trustSender) data.add("trustSender", "true");
     wat.xhr.send(wat.mail.LOAD_MAILCONTENT_URI, function(event) {
         // request complete
         var request = event.currentTarget,
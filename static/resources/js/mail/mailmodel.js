@@ -12,20 +12,44 @@ goog.require('wat');
 goog.require('goog.structs.Map');
 
 wat.mail.LOAD_MAILS_URI = "/mails";
+wat.mail.SEARCH_MAILS_URI = "/search";
+wat.mail.LOAD_CONVERSATIONS_URI = "/conversations";
+wat.mail.DOWNLOAD_ATTACHMENT_URI = "/attachment";
+wat.mail.COMPOSE_REPLY_URI = "/composeReply";
+wat.mail.SAVE_DRAFT_URI = "/saveDraft";
+wat.mail.LOAD_DRAFTS_URI = "/drafts";
+wat.mail.LOAD_DRAFT_URI = "/draft";
+wat.mail.DELETE_DRAFT_URI = "/deleteDraft";
 wat.mail.LOAD_MAILCONTENT_URI = "/mailContent";
+wat.mail.MAIL_SOURCE_URI = "/mailSource";
+wat.mail.DOWNLOAD_MAIL_URI = "/downloadMail";
+wat.mail.HEADER_FIELDS_URI = "/headers";
 wat.mail.TRASH_MAIL_URI = "/trashMail";
+wat.mail.DELETE_MAILS_URI = "/deleteMails";
+wat.mail.EMPTY_FOLDER_URI = "/emptyFolder";
+wat.mail.EXPUNGE_URI = "/expunge";
 wat.mail.MOVE_MAIL_URI = "/moveMail";
 wat.mail.UPDATE_FLAGS_URI = "/updateFlags";
+wat.mail.LOAD_KEYWORDS_URI = "/keywords";
+wat.mail.BULK_URI = "/bulk";
 wat.mail.CHECK_MAILS_URI = "/poll";
+wat.mail.MAIL_EVENTS_URI = "/events";
+wat.mail.LOAD_FOLDERS_URI = "/folders";
+wat.mail.FOLDER_STATUS_URI = "/folderStatus";
+wat.mail.CREATE_FOLDER_URI = "/createFolder";
+wat.mail.RENAME_FOLDER_URI = "/renameFolder";
+wat.mail.DELETE_FOLDER_URI = "/deleteFolder";
+wat.mail.SUBSCRIBE_FOLDER_URI = "/subscribeFolder";
 
 wat.mail.MailFlags = function(opt_Seen, opt_Deleted, opt_Answered, opt_Flagged, opt_Draft,
-                              opt_Recent) {
+                              opt_Recent, opt_Keywords) {
     this.Seen = opt_Seen;
     this.Deleted = opt_Deleted;
     this.Answered = opt_Answered;
     this.Flagged = opt_Flagged;
     this.Draft = opt_Draft;
     this.Recent = opt_Recent;
+    this.Keywords = opt_Keywords || [];
 };
 // Whether the mail has been read already
 wat.mail.MailFlags.prototype.Seen = false;
@@ -39,6 +63,8 @@ wat.mail.MailFlags.prototype.Flagged = false;
 wat.mail.MailFlags.prototype.Draft = false;
 // Message is "recently" arrived in this mailbox.
 wat.mail.MailFlags.prototype.Recent = false;
+// Custom keywords of the mail, e.g., $Forwarded, $Junk or labels like $label1
+wat.mail.MailFlags.prototype.Keywords = null;
 // Common used flags
 wat.mail.MailFlags.SEEN = new wat.mail.MailFlags(true, false, false, false, false, false);
 wat.mail.MailFlags.DELETED = new wat.mail.MailFlags(false, true, false, false, false, false);
@@ -57,9 +83,23 @@ wat.mail.MailHeader.prototype.Receiver = null;
 wat.mail.MailHeader.prototype.Sender = null;
 wat.mail.MailHeader.prototype.Size = null;
 // Whether the mail has been classified as spam and if so, with what degree
-// -1 - not been analysed | 0 - no spam | >0 - classified as spam (number tells the tool used)
+// -1 - not been analysed | 0 - no spam | >0 - classified as spam (normalized score 1 - 10)
 wat.mail.MailHeader.prototype.SpamIndicator = -1;
+// The spam filter, that classified the mail, e.g., "spamassassin" (empty, if not classified)
+wat.mail.MailHeader.prototype.SpamProvider = "";
 wat.mail.MailHeader.prototype.Subject = null;
+// The SPF, DKIM and DMARC results of the mail and the trust level derived from them
+wat.mail.MailHeader.prototype.Authenticity = {
+    SPF: "none",
+    DKIM: "none",
+    DMARC: "none",
+    // The domain, which signed the mail (empty, if the mail isn't signed)
+    DKIMDomain: "",
+    // The server, which checked the mail
+    AuthServID: "",
+    // One of: "verified" | "suspicious" | "unknown"
+    Trust: "unknown"
+};
 // The MIME information of this Mails header
 wat.mail.MailHeader.prototype.MimeHeader = {
     // Used version of the MIME protocol
@@ -83,6 +123,7 @@ wat.mail.ContentPart = function(jsonData) {
     this.Charset = jsonData.Charset;
     this.Encoding = jsonData.Encoding;
     this.Body = jsonData.Body;
+    this.RemoteContentBlocked = !!jsonData.RemoteContentBlocked;
 };
 // E.g., UTF-8
 wat.mail.ContentPart.prototype.Charset = "";
@@ -90,6 +131,8 @@ wat.mail.ContentPart.prototype.Charset = "";
 wat.mail.ContentPart.prototype.Encoding = "";
 // The text of this content part (could be plain, html, base64 encoded string)
 wat.mail.ContentPart.prototype.Body = "";
+// Whether remote images have been removed from the (sanitized) HTML body by the server
+wat.mail.ContentPart.prototype.RemoteContentBlocked = false;
 
 wat.mail.BaseMail = function(sender, receiver, subject, content) {
     this.Header = new wat.mail.MailHeader(sender, receiver, subject);
@@ -156,10 +199,14 @@ wat.mail.ReceivedMail = function(jsonData) {
     this.Header.Folder = jsonData.Header.Folder;
     this.Header.Size = jsonData.Header.Size;
     this.Header.SpamIndicator = jsonData.Header.SpamIndicator;
+    this.Header.SpamProvider = jsonData.Header.SpamProvider || "";
     this.Header.MimeHeader = jsonData.Header.MimeHeader;
+    if (goog.isDefAndNotNull(jsonData.Header.Authenticity)) {
+        this.Header.Authenticity = jsonData.Header.Authenticity;
+    }
     this.Flags = new wat.mail.MailFlags(jsonData.Flags.Seen, jsonData.Flags.Deleted,
         jsonData.Flags.Answered, jsonData.Flags.Flagged, jsonData.Flags.Draft,
-        jsonData.Flags.Recent);
+        jsonData.Flags.Recent, jsonData.Flags.Keywords);
 };
 goog.inherits(wat.mail.ReceivedMail, wat.mail.BaseMail);
 /**
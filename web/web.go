@@ -2,6 +2,7 @@ package web
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/go-martini/martini"
 	"github.com/gorilla/securecookie"
@@ -11,14 +12,19 @@ import (
 	"github.com/martini-contrib/sessions"
 	"hash/fnv"
 	"html/template"
+	"io"
+	"io/ioutil"
 	"log"
 	"mdrobek/watney/auth"
 	"mdrobek/watney/conf"
 	"mdrobek/watney/mail"
+	"mime"
 	"net/http"
 	"net/smtp"
+	"net/url"
 	"sort"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -39,6 +45,9 @@ type MailWeb struct {
 
 const TEMPLATE_GROUP_NAME string = "template_group"
 
+// Maximum number of bytes of uploaded files kept in memory (the rest is stored in temp files)
+const MAX_UPLOAD_MEMORY int64 = 32 << 20
+
 func NewWeb(mailConf *conf.MailConf, debug bool) *MailWeb {
 	var web *MailWeb = new(MailWeb)
 	web.mconf = mailConf
@@ -134,13 +143,37 @@ func (web *MailWeb) initHandlers() {
 	web.martini.Get("/main", sessionauth.LoginRequired, web.main)
 
 	web.martini.Post("/mailContent", sessionauth.LoginRequired, web.mailContent)
+	web.martini.Get("/attachment", sessionauth.LoginRequired, web.attachment)
+	web.martini.Get("/inline", sessionauth.LoginRequired, web.inlineImage)
+	web.martini.Get("/mailSource", sessionauth.LoginRequired, web.mailSource)
+	web.martini.Get("/downloadMail", sessionauth.LoginRequired, web.downloadMail)
+	web.martini.Post("/headers", sessionauth.LoginRequired, web.headerFields)
 	web.martini.Post("/mails", sessionauth.LoginRequired, web.mails)
+	web.martini.Post("/search", sessionauth.LoginRequired, web.search)
+	web.martini.Post("/conversations", sessionauth.LoginRequired, web.conversations)
 	web.martini.Post("/poll", sessionauth.LoginRequired, web.poll)
+	web.martini.Get("/events", sessionauth.LoginRequired, web.events)
 	web.martini.Post("/sendMail", sessionauth.LoginRequired, web.sendMail)
+	web.martini.Post("/composeReply", sessionauth.LoginRequired, web.composeReply)
+	web.martini.Post("/saveDraft", sessionauth.LoginRequired, web.saveDraft)
+	web.martini.Post("/drafts", sessionauth.LoginRequired, web.drafts)
+	web.martini.Post("/draft", sessionauth.LoginRequired, web.draft)
+	web.martini.Post("/deleteDraft", sessionauth.LoginRequired, web.deleteDraft)
 	web.martini.Post("/moveMail", sessionauth.LoginRequired, web.moveMail)
 	web.martini.Post("/trashMail", sessionauth.LoginRequired, web.trashMail)
+	web.martini.Post("/deleteMails", sessionauth.LoginRequired, web.deleteMails)
+	web.martini.Post("/emptyFolder", sessionauth.LoginRequired, web.emptyFolder)
+	web.martini.Post("/expunge", sessionauth.LoginRequired, web.expunge)
 	web.martini.Post("/updateFlags", sessionauth.LoginRequired, web.updateFlags)
+	web.martini.Post("/bulk", sessionauth.LoginRequired, web.bulk)
+	web.martini.Post("/keywords", sessionauth.LoginRequired, web.keywords)
 	web.martini.Post("/userInfo", sessionauth.LoginRequired, web.userInfo)
+	web.martini.Post("/folders", sessionauth.LoginRequired, web.folders)
+	web.martini.Post("/folderStatus", sessionauth.LoginRequired, web.folderStatus)
+	web.martini.Post("/createFolder", sessionauth.LoginRequired, web.createFolder)
+	web.martini.Post("/renameFolder", sessionauth.LoginRequired, web.renameFolder)
+	web.martini.Post("/deleteFolder", sessionauth.LoginRequired, web.deleteFolder)
+	web.martini.Post("/subscribeFolder", sessionauth.LoginRequired, web.subscribeFolder)
 
 	// Static content
 	web.martini.Use(martini.Static("static/resources/libs/",
@@ -191,6 +224,8 @@ func (web *MailWeb) authenticate(session sessions.Session, postedUser auth.Watne
 			h := fnv.New32a()
 			h.Write([]byte(postedUser.Username))
 			user.Id = int64(h.Sum32())
+			// Clean up the Trash and Junk folder in the background
+			go web.applyRetention(imapCon)
 			if err := sessionauth.AuthenticateSession(session, &user); err != nil {
 				r.HTML(200, "start", map[string]interface{}{
 					"FailedLogin": true,
@@ -236,40 +271,125 @@ func (web *MailWeb) poll(r render.Render, user sessionauth.User, session session
 		web.notifyAuthTimeout(r, "Poll for new mails")
 		return
 	}
-	// 2) Check, whether new mails have arrived since the last poll
-	// recentMails[0] -> nbr of new mails | recentMails[1] - last sequence number in mail list
-	if newMailSeqNbrs, err := watneyUser.ImapCon.CheckNewMails(); err != nil {
-		// 2a) Check for new mails failed for some reason
-		web.notifyError(r, 500, fmt.Sprintf("Error while checking for new mails"), err.Error())
-	} else if len(newMailSeqNbrs) > 0 {
-		// 2b) If new mails have arrived, load them from the mail server
-		if mails, err := watneyUser.ImapCon.LoadNMailsFromFolderWithSeqNbrs("/", newMailSeqNbrs); err == nil {
-			// If the number of loaded mails is not equal to the number of new mail UIDs, send error
-			if len(newMailSeqNbrs) != len(mails) {
-				web.notifyError(r, 500,
-					fmt.Sprintf("New mails are available, but they couldn't be retrieved"),
-					fmt.Sprintf("Expected %d mail(s), but could only load %d mail(s)",
-						len(newMailSeqNbrs), len(mails)))
+	// 2) Collect the mails, that have arrived since the last poll in the given folder (default:
+	//	  root). They are announced by the same events, that are streamed to all listeners (see
+	//	  events), so polling doesn't take the new mails away from them.
+	var (
+		folder string      = req.FormValue("folder")
+		mails  []mail.Mail = make([]mail.Mail, 0)
+	)
+	if 0 == len(folder) {
+		folder = "/"
+	}
+	for _, event := range watneyUser.ImapCon.PollEvents() {
+		if event.Type == mail.NEW_MAIL_EVENT && event.Folder == folder {
+			mails = append(mails, copyMails(event.Mails)...)
+		}
+	}
+	// 3) Return the newest mails first as json
+	sort.Sort(mail.MailSlice(mails))
+	web.sanitizeMails(watneyUser, mails)
+	r.JSON(200, mails)
+}
+
+/**
+ * Handler that streams all folder updates (new mails, expunged mails, flag changes) to the browser
+ * as Server-Sent Events, as soon as they are pushed by the IMAP server:
+ *	event: newMail | expunge | flags
+ *	data: JSON of the mail.MailEvent (newMail events also contain the new mails)
+ */
+func (web *MailWeb) events(w http.ResponseWriter, r render.Render, user sessionauth.User,
+	req *http.Request) {
+	var watneyUser *auth.WatneyUser = user.(*auth.WatneyUser)
+	// 1) Check for authentication status, and return if no user is given or not authenticated
+	if nil == watneyUser || !watneyUser.IsAuthenticated() {
+		web.notifyAuthTimeout(r, "Listen for mail events")
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		web.notifyError(r, 500, "Mail events can't be streamed",
+			"ResponseWriter doesn't support flushing")
+		return
+	}
+	// 2) Register for updates of the IMAP connection and start the event stream
+	var (
+		events    chan mail.MailEvent = watneyUser.ImapCon.AddEventListener()
+		heartbeat *time.Ticker        = time.NewTicker(30 * time.Second)
+		closed    <-chan bool
+	)
+	defer watneyUser.ImapCon.RemoveEventListener(events)
+	defer heartbeat.Stop()
+	if notifier, ok := w.(http.CloseNotifier); ok {
+		closed = notifier.CloseNotify()
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(200)
+	flusher.Flush()
+	// 3) Forward all events, until either the browser or the IMAP connection is closed
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
 				return
 			}
-			// Reverse the retrieved mail array
-			sort.Sort(mail.MailSlice(mails))
-			// Return the mails as json
-			r.JSON(200, mails)
+			if event.Type == mail.NEW_MAIL_EVENT {
+				// The mails are shared with all other listeners => only sanitize a copy
+				event.Mails = copyMails(event.Mails)
+				web.sanitizeMails(watneyUser, event.Mails)
+			}
+			if err := writeEvent(w, event.Type, event); err != nil {
+				fmt.Printf("[watney] Couldn't write mail event: %s\n", err.Error())
+				return
+			}
+			flusher.Flush()
+		case <-heartbeat.C:
+			// Comment line to keep proxies from closing the idle connection
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-closed:
 			return
 		}
-		// TODO: If the mail loading failed, the information about the recent mails
-		//		 will be lost at that point => counteract
-		web.notifyError(r, 500,
-			fmt.Sprintf("New mails are available, but they couldn't be retrieved"), err.Error())
-		return
 	}
-	// 3) No new mails have arrived
-	r.JSON(200, make([]mail.Mail, 0))
+}
+
+/**
+ * @return A copy of the given mails, whose content can be sanitized without modifying the given
+ *		   mails
+ */
+func copyMails(mails []mail.Mail) []mail.Mail {
+	var copied []mail.Mail = make([]mail.Mail, len(mails))
+	for i, curMail := range mails {
+		copied[i] = curMail
+		copied[i].Content = make(mail.Content, len(curMail.Content))
+		for contentType, part := range curMail.Content {
+			copied[i].Content[contentType] = part
+		}
+	}
+	return copied
+}
+
+/**
+ * Writes one Server-Sent Event with the given name and the JSON encoded data.
+ */
+func writeEvent(w io.Writer, name string, data interface{}) error {
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, jsonData)
+	return err
 }
 
 /**
  * Handler to load all mails for a given folder.
+ * If the form value 'limit' is given, only one page of mails is returned (see mailPage).
+ * If the form value 'thread' is given, the mails are grouped into threads (see mailThreads).
+ * If the form value 'sort' is given, the mails are sorted by that criterion (see sortedMails).
  */
 func (web *MailWeb) mails(r render.Render, user sessionauth.User, req *http.Request) {
 	var (
@@ -277,6 +397,16 @@ func (web *MailWeb) mails(r render.Render, user sessionauth.User, req *http.Requ
 		watneyUser *auth.WatneyUser = user.(*auth.WatneyUser)
 	)
 	if nil != watneyUser && watneyUser.IsAuthenticated() {
+		if len(req.FormValue("limit")) > 0 {
+			web.mailPage(r, watneyUser, req)
+			return
+		} else if len(req.FormValue("thread")) > 0 {
+			web.mailThreads(r, watneyUser, req)
+			return
+		} else if len(req.FormValue("sort")) > 0 {
+			web.sortedMails(r, watneyUser, req)
+			return
+		}
 		switch req.FormValue("mailInformation") {
 		case mail.FULL:
 			mails, _ = watneyUser.ImapCon.LoadAllMailsFromFolder(req.FormValue("mailbox"))
@@ -287,12 +417,233 @@ func (web *MailWeb) mails(r render.Render, user sessionauth.User, req *http.Requ
 		}
 		// Reverse the retrieved mail array
 		sort.Sort(mail.MailSlice(mails))
+		web.sanitizeMails(watneyUser, mails)
 		r.JSON(200, mails)
 	} else {
 		web.notifyAuthTimeout(r, "Retrieve mail overview")
 	}
 }
 
+/**
+ * Loads all mails of the given folder sorted by the server, if possible. Form values:
+ *	- sort: The sort criterion (date, arrival, from, subject, size)
+ *	- reverse: Whether to sort in descending order (default: false)
+ */
+func (web *MailWeb) sortedMails(r render.Render, watneyUser *auth.WatneyUser, req *http.Request) {
+	var (
+		folder      string = req.FormValue("mailbox")
+		withContent bool   = req.FormValue("mailInformation") == mail.FULL
+		reverse     bool
+		err         error
+	)
+	if len(req.FormValue("reverse")) > 0 {
+		if reverse, err = strconv.ParseBool(req.FormValue("reverse")); err != nil {
+			web.notifyError(r, 200,
+				fmt.Sprintf("Given reverse flag '%s' is not a boolean", req.FormValue("reverse")),
+				err.Error())
+			return
+		}
+	}
+	mails, err := watneyUser.ImapCon.SortMails(folder, req.FormValue("sort"), reverse,
+		withContent)
+	if err != nil {
+		web.notifyError(r, 500, fmt.Sprintf("Mails of folder '%s' couldn't be sorted", folder),
+			err.Error())
+		return
+	}
+	web.sanitizeMails(watneyUser, mails)
+	r.JSON(200, mails)
+}
+
+/**
+ * Groups all mails of the given folder into threads and returns the root nodes of all threads.
+ * Form values:
+ *	- thread: The thread algorithm (references, orderedsubject)
+ */
+func (web *MailWeb) mailThreads(r render.Render, watneyUser *auth.WatneyUser, req *http.Request) {
+	var folder string = req.FormValue("mailbox")
+	threads, err := watneyUser.ImapCon.ThreadMails(folder, req.FormValue("thread"))
+	if err != nil {
+		web.notifyError(r, 500, fmt.Sprintf("Threads of folder '%s' couldn't be built", folder),
+			err.Error())
+		return
+	}
+	r.JSON(200, threads)
+}
+
+/**
+ * Loads one page of mails (newest first) for the given folder and returns it together with the
+ * total number of mails in the folder. Form values:
+ *	- limit: The maximum number of mails on the page
+ *	- offset: The number of newest mails to skip (default: 0)
+ *	- beforeUID: If given, the page starts with the newest mail whose UID is below this UID
+ *				 (takes precedence over 'offset')
+ */
+func (web *MailWeb) mailPage(r render.Render, watneyUser *auth.WatneyUser, req *http.Request) {
+	var (
+		folder      string = req.FormValue("mailbox")
+		withContent bool   = req.FormValue("mailInformation") == mail.FULL
+		offset      int64
+		beforeUID   uint64
+		page        mail.MailPage
+	)
+	limit, err := strconv.ParseInt(req.FormValue("limit"), 10, 32)
+	if err != nil {
+		web.notifyError(r, 200,
+			fmt.Sprintf("Given limit '%s' is not a valid number", req.FormValue("limit")),
+			err.Error())
+		return
+	}
+	if len(req.FormValue("beforeUID")) > 0 {
+		if beforeUID, err = strconv.ParseUint(req.FormValue("beforeUID"), 10, 32); err != nil {
+			web.notifyError(r, 200,
+				fmt.Sprintf("Given UID '%s' is not a valid ID", req.FormValue("beforeUID")),
+				err.Error())
+			return
+		}
+		page, err = watneyUser.ImapCon.LoadMailPageBeforeUID(folder, uint32(beforeUID),
+			int(limit), withContent)
+	} else {
+		if len(req.FormValue("offset")) > 0 {
+			if offset, err = strconv.ParseInt(req.FormValue("offset"), 10, 32); err != nil {
+				web.notifyError(r, 200,
+					fmt.Sprintf("Given offset '%s' is not a valid number",
+						req.FormValue("offset")), err.Error())
+				return
+			}
+		}
+		page, err = watneyUser.ImapCon.LoadMailPage(folder, int(offset), int(limit), withContent)
+	}
+	if err != nil {
+		web.notifyError(r, 500, fmt.Sprintf("Mails of folder '%s' couldn't be loaded", folder),
+			err.Error())
+		return
+	}
+	web.sanitizeMails(watneyUser, page.Mails)
+	r.JSON(200, page)
+}
+
+/**
+ * Handler to search one or more folders for mails. Form values:
+ *	- folders: Comma separated list of folders to search in (default: root)
+ *	- from, to, cc, subject, body, text: Strings the respective mail part has to contain
+ *	- since, before: Dates (YYYY-MM-DD) the mail has been received on/after or before
+ *	- larger, smaller: Size limits of the mail in bytes
+ *	- withFlags, withoutFlags: JSON encoded mail.Flags the mail has to have or must not have set
+ *	- header: "Field: value" the given header field has to contain (can be repeated)
+ * @return The overviews of all matching mails (newest first)
+ */
+func (web *MailWeb) search(r render.Render, curUser sessionauth.User, req *http.Request) {
+	var watneyUser *auth.WatneyUser = curUser.(*auth.WatneyUser)
+	if !watneyUser.ImapCon.IsAuthenticated() {
+		web.notifyAuthTimeout(r, "Search mails")
+		return
+	}
+	var (
+		folders []string = []string{"/"}
+		query   *mail.SearchQuery
+		err     error
+	)
+	if query, err = parseSearchQuery(req); err != nil {
+		web.notifyError(r, 200, "Invalid search query", err.Error())
+		return
+	}
+	if len(req.FormValue("folders")) > 0 {
+		folders = strings.Split(req.FormValue("folders"), ",")
+	}
+	if mails, err := watneyUser.ImapCon.SearchMails(folders, query); err != nil {
+		web.notifyError(r, 500, "Search for mails failed", err.Error())
+	} else {
+		r.JSON(200, mails)
+	}
+}
+
+/**
+ * Handler to group the mails of one or more folders into conversations. Form values:
+ *	- folders: Comma separated list of folders (default: root and the Sent folder)
+ * @return All conversations with their thread tree, mails and folders (newest first)
+ */
+func (web *MailWeb) conversations(r render.Render, curUser sessionauth.User,
+	req *http.Request) {
+	var watneyUser *auth.WatneyUser = curUser.(*auth.WatneyUser)
+	if !watneyUser.ImapCon.IsAuthenticated() {
+		web.notifyAuthTimeout(r, "Load conversations")
+		return
+	}
+	var folders []string = []string{"/",
+		watneyUser.ImapCon.SpecialFolder(mail.SPECIAL_USE_SENT)}
+	if len(req.FormValue("folders")) > 0 {
+		folders = strings.Split(req.FormValue("folders"), ",")
+	}
+	if conversations, err := watneyUser.ImapCon.LoadConversations(folders); err != nil {
+		web.notifyError(r, 500, "Conversations couldn't be loaded", err.Error())
+	} else {
+		r.JSON(200, conversations)
+	}
+}
+
+/**
+ * Builds the search query from the form values of the given search request (see search).
+ */
+func parseSearchQuery(req *http.Request) (*mail.SearchQuery, error) {
+	var (
+		query *mail.SearchQuery = &mail.SearchQuery{
+			From:    req.FormValue("from"),
+			To:      req.FormValue("to"),
+			Cc:      req.FormValue("cc"),
+			Subject: req.FormValue("subject"),
+			Body:    req.FormValue("body"),
+			Text:    req.FormValue("text"),
+			Header:  make(map[string]string),
+		}
+		err error
+	)
+	for key, date := range map[string]*time.Time{"since": &query.Since, "before": &query.Before} {
+		if len(req.FormValue(key)) > 0 {
+			if *date, err = time.Parse("2006-01-02", req.FormValue(key)); err != nil {
+				return nil, fmt.Errorf("Invalid date '%s' for '%s', expected YYYY-MM-DD",
+					req.FormValue(key), key)
+			}
+		}
+	}
+	for key, size := range map[string]*uint32{"larger": &query.Larger, "smaller": &query.Smaller} {
+		if len(req.FormValue(key)) > 0 {
+			value, err := strconv.ParseUint(req.FormValue(key), 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid size '%s' for '%s'", req.FormValue(key), key)
+			}
+			*size = uint32(value)
+		}
+	}
+	for key, flags := range map[string]**mail.Flags{"withFlags": &query.WithFlags,
+		"withoutFlags": &query.WithoutFlags} {
+		if len(req.FormValue(key)) > 0 {
+			*flags = &mail.Flags{}
+			if err = json.Unmarshal([]byte(req.FormValue(key)), *flags); err != nil {
+				return nil, fmt.Errorf("Invalid flags '%s' for '%s'", req.FormValue(key), key)
+			}
+		}
+	}
+	req.ParseForm()
+	for _, header := range req.Form["header"] {
+		if parts := strings.SplitN(header, ":", 2); len(parts) == 2 {
+			query.Header[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+		} else {
+			return nil, fmt.Errorf("Invalid header criteria '%s', expected 'Field: value'", header)
+		}
+	}
+	return query, nil
+}
+
+/**
+ * Handler to load the content of a mail. The HTML part is sanitized and remote images are blocked,
+ * unless they are allowed for the mail or its sender. Form values:
+ *	- folder: The folder of the mail
+ *	- uid: The UID of the mail
+ *	- allowRemote: "true", to load remote content of this mail (optional)
+ *	- trustSender: "true", to load remote content of all mails of the sender (optional). The
+ *	  sender is only trusted until the session ends (see MailCon.TrustSender).
+ */
 func (web *MailWeb) mailContent(r render.Render, user sessionauth.User, req *http.Request) {
 	var (
 		watneyUser *auth.WatneyUser = user.(*auth.WatneyUser)
@@ -311,22 +662,243 @@ func (web *MailWeb) mailContent(r render.Render, user sessionauth.User, req *htt
 			err.Error())
 		return
 	}
+	if "true" == req.FormValue("trustSender") && nil != mail.Header && len(mail.Header.From) > 0 {
+		watneyUser.ImapCon.TrustSender(mail.Header.From[0].Address)
+	}
+	web.sanitizeMail(watneyUser, &mail, "true" == req.FormValue("allowRemote"))
 	r.JSON(200, mail.Content)
 }
 
+/**
+ * Sanitizes the HTML part of all given mails (see sanitizeMail), before they are sent to the
+ * browser. Remote content is only loaded for mails of trusted senders.
+ */
+func (web *MailWeb) sanitizeMails(watneyUser *auth.WatneyUser, mails []mail.Mail) {
+	for i := range mails {
+		web.sanitizeMail(watneyUser, &mails[i], false)
+	}
+}
+
+/**
+ * Sanitizes the HTML part of the given mail and blocks its remote content, unless it is allowed
+ * for this mail or its sender is trusted (see MailCon.TrustSender).
+ */
+func (web *MailWeb) sanitizeMail(watneyUser *auth.WatneyUser, curMail *mail.Mail,
+	allowRemote bool) {
+	if 0 == len(curMail.Content) {
+		return
+	}
+	var folder string
+	if nil != curMail.Header {
+		folder = curMail.Header.Folder
+		if len(curMail.Header.From) > 0 {
+			allowRemote = allowRemote ||
+				watneyUser.ImapCon.IsTrustedSender(curMail.Header.From[0].Address)
+		}
+	}
+	// Inline images are loaded from this server with the session of the user
+	curMail.Content.Sanitize(!allowRemote, curMail.InlineURLs(func(contentID string) string {
+		return fmt.Sprintf("/inline?folder=%s&uid=%d&cid=%s", url.QueryEscape(folder),
+			curMail.UID, url.QueryEscape(contentID))
+	}))
+}
+
+/**
+ * Handler to load an inline image of a mail, which is referenced by a cid: URL in its HTML part.
+ * Form values:
+ *	- folder: The folder of the mail
+ *	- uid: The UID of the mail
+ *	- cid: The Content-ID of the image
+ */
+func (web *MailWeb) inlineImage(r render.Render, w http.ResponseWriter, user sessionauth.User,
+	req *http.Request) {
+	var watneyUser *auth.WatneyUser = user.(*auth.WatneyUser)
+	if !watneyUser.ImapCon.IsAuthenticated() {
+		web.notifyAuthTimeout(r, "Load inline image")
+		return
+	}
+	uid, err := strconv.ParseUint(req.FormValue("uid"), 10, 32)
+	if err != nil {
+		web.notifyError(r, 200,
+			fmt.Sprintf("Given UID '%s' is not a valid ID", req.FormValue("uid")), err.Error())
+		return
+	}
+	part, body, err := watneyUser.ImapCon.LoadInlinePart(req.FormValue("folder"), uint32(uid),
+		req.FormValue("cid"))
+	if err != nil {
+		web.notifyError(r, 500, fmt.Sprintf("Loading inline image '%s' of mail (%d, %s) failed",
+			req.FormValue("cid"), uid, req.FormValue("folder")), err.Error())
+		return
+	}
+	// The image belongs to a single mail and never changes => the browser may cache it
+	w.Header().Set("Content-Type", part.ContentType)
+	w.Header().Set("Content-Disposition", mail.DISPOSITION_INLINE)
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+/**
+ * Handler to download a single body part of a mail, e.g., an attachment. Form values:
+ *	- folder: The folder of the mail
+ *	- uid: The UID of the mail
+ *	- section: The IMAP section specifier of the part (see mail.MailPart)
+ */
+func (web *MailWeb) attachment(r render.Render, w http.ResponseWriter, user sessionauth.User,
+	req *http.Request) {
+	var watneyUser *auth.WatneyUser = user.(*auth.WatneyUser)
+	if !watneyUser.ImapCon.IsAuthenticated() {
+		web.notifyAuthTimeout(r, "Download attachment")
+		return
+	}
+	uid, err := strconv.ParseUint(req.FormValue("uid"), 10, 32)
+	if err != nil {
+		web.notifyError(r, 200,
+			fmt.Sprintf("Given UID '%s' is not a valid ID", req.FormValue("uid")), err.Error())
+		return
+	}
+	part, body, err := watneyUser.ImapCon.LoadMailPart(req.FormValue("folder"), uint32(uid),
+		req.FormValue("section"))
+	if err != nil {
+		web.notifyError(r, 500, fmt.Sprintf("Loading part '%s' of mail (%d, %s) failed",
+			req.FormValue("section"), uid, req.FormValue("folder")), err.Error())
+		return
+	}
+	var filename string = part.Filename
+	if 0 == len(filename) {
+		filename = fmt.Sprintf("part-%s", part.Section)
+	}
+	// 1) Non-ASCII file names can't be formatted as plain parameter => use a generic one instead
+	disposition := mime.FormatMediaType(mail.DISPOSITION_ATTACHMENT,
+		map[string]string{"filename": filename})
+	if 0 == len(disposition) {
+		disposition = mime.FormatMediaType(mail.DISPOSITION_ATTACHMENT,
+			map[string]string{"filename": fmt.Sprintf("part-%s", part.Section)})
+	}
+	// 2) Stream the decoded body with its original Content-Type
+	w.Header().Set("Content-Type", part.ContentType)
+	w.Header().Set("Content-Disposition", disposition)
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+/**
+ * Handler to view the raw source of a mail (including all headers) as plain text. Form values:
+ *	- folder: The folder of the mail
+ *	- uid: The UID of the mail
+ */
+func (web *MailWeb) mailSource(r render.Render, w http.ResponseWriter, user sessionauth.User,
+	req *http.Request) {
+	uid, raw, ok := web.loadRawMail(r, user, req)
+	if !ok {
+		return
+	}
+	// The source may contain 8-bit text in any charset => serve it as is
+	w.Header().Set("Content-Type", "text/plain")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType(mail.DISPOSITION_INLINE,
+		map[string]string{"filename": fmt.Sprintf("mail-%d.txt", uid)}))
+	web.writeRawMail(w, raw)
+}
+
+/**
+ * Handler to download the raw source of a mail as .eml file (message/rfc822). Form values:
+ *	- folder: The folder of the mail
+ *	- uid: The UID of the mail
+ */
+func (web *MailWeb) downloadMail(r render.Render, w http.ResponseWriter, user sessionauth.User,
+	req *http.Request) {
+	uid, raw, ok := web.loadRawMail(r, user, req)
+	if !ok {
+		return
+	}
+	// Non-ASCII file names can't be formatted as plain parameter => use a generic one instead
+	disposition := mime.FormatMediaType(mail.DISPOSITION_ATTACHMENT,
+		map[string]string{"filename": mail.EMLFilename(raw, uid)})
+	if 0 == len(disposition) {
+		disposition = mime.FormatMediaType(mail.DISPOSITION_ATTACHMENT,
+			map[string]string{"filename": fmt.Sprintf("mail-%d.eml", uid)})
+	}
+	w.Header().Set("Content-Type", "message/rfc822")
+	w.Header().Set("Content-Disposition", disposition)
+	web.writeRawMail(w, raw)
+}
+
+/**
+ * Handler to load all header fields of a mail in their original order. Form values:
+ *	- folder: The folder of the mail
+ *	- uid: The UID of the mail
+ */
+func (web *MailWeb) headerFields(r render.Render, curUser sessionauth.User, req *http.Request) {
+	var watneyUser *auth.WatneyUser = curUser.(*auth.WatneyUser)
+	if !watneyUser.ImapCon.IsAuthenticated() {
+		web.notifyAuthTimeout(r, "Load header fields")
+		return
+	}
+	uid, err := strconv.ParseUint(req.FormValue("uid"), 10, 32)
+	if err != nil {
+		web.notifyError(r, 200,
+			fmt.Sprintf("Given UID '%s' is not a valid ID", req.FormValue("uid")), err.Error())
+		return
+	}
+	fields, err := watneyUser.ImapCon.LoadHeaderFields(req.FormValue("folder"), uint32(uid))
+	if err != nil {
+		web.notifyError(r, 500, fmt.Sprintf("Loading header fields of mail (%d, %s) failed", uid,
+			req.FormValue("folder")), err.Error())
+		return
+	}
+	r.JSON(200, fields)
+}
+
+/**
+ * Loads the raw source of the mail given by the form values 'folder' and 'uid'.
+ * @return The UID and the source of the mail and false, if an error has been sent already
+ */
+func (web *MailWeb) loadRawMail(r render.Render, user sessionauth.User,
+	req *http.Request) (uint32, []byte, bool) {
+	var watneyUser *auth.WatneyUser = user.(*auth.WatneyUser)
+	if !watneyUser.ImapCon.IsAuthenticated() {
+		web.notifyAuthTimeout(r, "Load mail source")
+		return 0, nil, false
+	}
+	uid, err := strconv.ParseUint(req.FormValue("uid"), 10, 32)
+	if err != nil {
+		web.notifyError(r, 200,
+			fmt.Sprintf("Given UID '%s' is not a valid ID", req.FormValue("uid")), err.Error())
+		return 0, nil, false
+	}
+	raw, err := watneyUser.ImapCon.LoadRawMail(req.FormValue("folder"), uint32(uid))
+	if err != nil {
+		web.notifyError(r, 500, fmt.Sprintf("Loading source of mail (%d, %s) failed", uid,
+			req.FormValue("folder")), err.Error())
+		return 0, nil, false
+	}
+	return uint32(uid), raw, true
+}
+
+func (web *MailWeb) writeRawMail(w http.ResponseWriter, raw []byte) {
+	w.Header().Set("Content-Length", strconv.Itoa(len(raw)))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	w.Write(raw)
+}
+
 func (web *MailWeb) sendMail(r render.Render, curUser sessionauth.User, req *http.Request) {
 	var watneyUser *auth.WatneyUser = curUser.(*auth.WatneyUser)
 	if watneyUser.ImapCon.IsAuthenticated() {
-		var subject, from, body string
-		subject = req.FormValue("subject")
-		from = req.FormValue("from")
-		to := []string{req.FormValue("to")}
-		body = req.FormValue("body")
-		//		fmt.Printf("%s -> %s : %s\n%s", from, to, subject, body)
-		err := watneyUser.ImapCon.SendMail(watneyUser.SMTPAuth, from, to, subject, body)
+		outgoing, desc, err := web.readOutgoingMail(watneyUser, req)
+		if err != nil {
+			web.notifyError(r, 200, desc, err.Error())
+			return
+		}
+		err = watneyUser.ImapCon.SendMail(watneyUser.SMTPAuth, outgoing)
 		if err != nil {
 			web.notifyError(r, 200,
-				fmt.Sprintf("Mail couldn't be sent to '%s'", to), err.Error())
+				fmt.Sprintf("Mail couldn't be sent to '%s'", strings.Join(outgoing.To, ", ")),
+				err.Error())
 		} else {
 			r.JSON(200, nil)
 		}
@@ -335,6 +907,219 @@ func (web *MailWeb) sendMail(r render.Render, curUser sessionauth.User, req *htt
 	}
 }
 
+/**
+ * Handler to save the currently composed mail as draft (same form values as sendMail). The draft
+ * given by the form value draftUID is replaced by the new version.
+ * @return {"uid": The UID of the saved draft}
+ */
+func (web *MailWeb) saveDraft(r render.Render, curUser sessionauth.User, req *http.Request) {
+	var watneyUser *auth.WatneyUser = curUser.(*auth.WatneyUser)
+	if !watneyUser.ImapCon.IsAuthenticated() {
+		web.notifyAuthTimeout(r, "Save draft")
+		return
+	}
+	outgoing, desc, err := web.readOutgoingMail(watneyUser, req)
+	if err != nil {
+		web.notifyError(r, 200, desc, err.Error())
+		return
+	}
+	uid, err := watneyUser.ImapCon.SaveDraft(outgoing, outgoing.DraftUID)
+	if err != nil {
+		web.notifyError(r, 500, "Draft couldn't be saved", err.Error())
+		return
+	}
+	r.JSON(200, map[string]uint32{"uid": uid})
+}
+
+func (web *MailWeb) drafts(r render.Render, curUser sessionauth.User, req *http.Request) {
+	var watneyUser *auth.WatneyUser = curUser.(*auth.WatneyUser)
+	if !watneyUser.ImapCon.IsAuthenticated() {
+		web.notifyAuthTimeout(r, "Load drafts")
+		return
+	}
+	drafts, err := watneyUser.ImapCon.LoadDrafts()
+	if err != nil {
+		web.notifyError(r, 500, "Drafts couldn't be loaded", err.Error())
+		return
+	}
+	r.JSON(200, drafts)
+}
+
+/**
+ * Handler to resume composing the draft with the given UID (form value uid).
+ * @return The draft (its attachments are only listed, they are kept, when the draft is saved again
+ *		   or sent with the form value draftUID)
+ */
+func (web *MailWeb) draft(r render.Render, curUser sessionauth.User, req *http.Request) {
+	var watneyUser *auth.WatneyUser = curUser.(*auth.WatneyUser)
+	if !watneyUser.ImapCon.IsAuthenticated() {
+		web.notifyAuthTimeout(r, "Load draft")
+		return
+	}
+	uid, err := strconv.ParseUint(req.FormValue("uid"), 10, 32)
+	if err != nil {
+		web.notifyError(r, 200,
+			fmt.Sprintf("Given UID '%s' is not a valid ID", req.FormValue("uid")), err.Error())
+		return
+	}
+	draft, err := watneyUser.ImapCon.LoadDraft(uint32(uid))
+	if err != nil {
+		web.notifyError(r, 500, fmt.Sprintf("Draft %d couldn't be loaded", uid), err.Error())
+		return
+	}
+	r.JSON(200, draft)
+}
+
+func (web *MailWeb) deleteDraft(r render.Render, curUser sessionauth.User, req *http.Request) {
+	var watneyUser *auth.WatneyUser = curUser.(*auth.WatneyUser)
+	if !watneyUser.ImapCon.IsAuthenticated() {
+		web.notifyAuthTimeout(r, "Delete draft")
+		return
+	}
+	uid, err := strconv.ParseUint(req.FormValue("uid"), 10, 32)
+	if err != nil {
+		web.notifyError(r, 200,
+			fmt.Sprintf("Given UID '%s' is not a valid ID", req.FormValue("uid")), err.Error())
+		return
+	}
+	if err = watneyUser.ImapCon.DeleteDraft(uint32(uid)); err != nil {
+		web.notifyError(r, 500, fmt.Sprintf("Draft %d couldn't be deleted", uid), err.Error())
+		return
+	}
+	r.JSON(200, nil)
+}
+
+/**
+ * Reads the composed mail from the form values from, to, cc, bcc, replyTo, subject, body, html
+ * and the uploaded attachments. Optional form values:
+ *	- originFolder, originUID, originType: The original mail of a reply or forward
+ *	- draftUID: The draft the mail has been composed from (its attachments are kept)
+ * @return The mail or a description of the failure with the error
+ */
+func (web *MailWeb) readOutgoingMail(watneyUser *auth.WatneyUser,
+	req *http.Request) (*mail.OutgoingMail, string, error) {
+	var (
+		outgoing *mail.OutgoingMail = &mail.OutgoingMail{
+			From:    req.FormValue("from"),
+			Subject: req.FormValue("subject"),
+			Text:    req.FormValue("body"),
+			HTML:    req.FormValue("html"),
+		}
+		err error
+	)
+	if outgoing.Attachments, err = readAttachments(req); err != nil {
+		return nil, "Attachments couldn't be read", err
+	}
+	// Receivers can be given as repeated or comma separated form values
+	outgoing.To, outgoing.Cc, outgoing.Bcc = req.Form["to"], req.Form["cc"], req.Form["bcc"]
+	outgoing.ReplyTo = req.Form["replyTo"]
+	// Drafts already contain the attachments uploaded before (and those of a forward)
+	if len(req.FormValue("draftUID")) > 0 {
+		uid, err := strconv.ParseUint(req.FormValue("draftUID"), 10, 32)
+		if err != nil {
+			return nil, fmt.Sprintf("Given UID '%s' is not a valid ID",
+				req.FormValue("draftUID")), err
+		}
+		draft, err := watneyUser.ImapCon.LoadDraft(uint32(uid))
+		if err != nil {
+			return nil, fmt.Sprintf("Draft %d couldn't be loaded", uid), err
+		}
+		outgoing.DraftUID = uint32(uid)
+		outgoing.Attachments = append(draft.Attachments, outgoing.Attachments...)
+	}
+	// Replies and forwards refer to their original mail (see composeReply)
+	if len(req.FormValue("originUID")) > 0 {
+		if err = web.addOrigin(watneyUser, outgoing, req); err != nil {
+			return nil, "Original mail of the reply couldn't be loaded", err
+		}
+	}
+	return outgoing, "", nil
+}
+
+/**
+ * Handler to create the draft of a reply, reply-all or forward of a mail. Form values:
+ *	- folder: The folder of the original mail
+ *	- uid: The UID of the original mail
+ *	- type: reply | replyAll | forward
+ * @return The draft (the attachments of a forward are only listed, they are added again, when the
+ *		   draft is sent with the form values originFolder, originUID and originType)
+ */
+func (web *MailWeb) composeReply(r render.Render, curUser sessionauth.User, req *http.Request) {
+	var watneyUser *auth.WatneyUser = curUser.(*auth.WatneyUser)
+	if !watneyUser.ImapCon.IsAuthenticated() {
+		web.notifyAuthTimeout(r, "Compose reply")
+		return
+	}
+	uid, err := strconv.ParseUint(req.FormValue("uid"), 10, 32)
+	if err != nil {
+		web.notifyError(r, 200,
+			fmt.Sprintf("Given UID '%s' is not a valid ID", req.FormValue("uid")), err.Error())
+		return
+	}
+	draft, err := watneyUser.ImapCon.ComposeReply(req.FormValue("folder"), uint32(uid),
+		req.FormValue("type"), watneyUser.Username)
+	if err != nil {
+		web.notifyError(r, 500, fmt.Sprintf("Reply to mail (%d, %s) couldn't be created", uid,
+			req.FormValue("folder")), err.Error())
+		return
+	}
+	r.JSON(200, draft)
+}
+
+/**
+ * Links the given outgoing mail to its original mail given by the form values originFolder,
+ * originUID and originType: sets the threading headers and adds the attachments of a forward.
+ */
+func (web *MailWeb) addOrigin(watneyUser *auth.WatneyUser, outgoing *mail.OutgoingMail,
+	req *http.Request) error {
+	uid, err := strconv.ParseUint(req.FormValue("originUID"), 10, 32)
+	if err != nil {
+		return fmt.Errorf("Given UID '%s' is not a valid ID", req.FormValue("originUID"))
+	}
+	draft, err := watneyUser.ImapCon.ComposeReply(req.FormValue("originFolder"), uint32(uid),
+		req.FormValue("originType"), outgoing.From)
+	if err != nil {
+		return err
+	}
+	outgoing.InReplyTo, outgoing.References = draft.InReplyTo, draft.References
+	outgoing.Origin = draft.Origin
+	// The attachments of a forward have been saved with its draft already
+	if 0 == outgoing.DraftUID {
+		outgoing.Attachments = append(draft.Attachments, outgoing.Attachments...)
+	}
+	return nil
+}
+
+/**
+ * Reads all files uploaded as 'attachments' of a multipart/form-data request.
+ * @return No attachments, if the request isn't a multipart request
+ */
+func readAttachments(req *http.Request) ([]mail.Attachment, error) {
+	var attachments []mail.Attachment = []mail.Attachment{}
+	if err := req.ParseMultipartForm(MAX_UPLOAD_MEMORY); err == http.ErrNotMultipart {
+		return attachments, nil
+	} else if err != nil {
+		return attachments, err
+	}
+	for _, fileHeader := range req.MultipartForm.File["attachments"] {
+		file, err := fileHeader.Open()
+		if err != nil {
+			return attachments, err
+		}
+		data, err := ioutil.ReadAll(file)
+		file.Close()
+		if err != nil {
+			return attachments, err
+		}
+		attachments = append(attachments, mail.Attachment{
+			Filename:    fileHeader.Filename,
+			ContentType: fileHeader.Header.Get("Content-Type"),
+			Data:        data,
+		})
+	}
+	return attachments, nil
+}
+
 func (web *MailWeb) moveMail(r render.Render, curUser sessionauth.User, req *http.Request) {
 	var watneyUser *auth.WatneyUser = curUser.(*auth.WatneyUser)
 	if watneyUser.ImapCon.IsAuthenticated() {
@@ -374,6 +1159,92 @@ func (web *MailWeb) trashMail(r render.Render, curUser sessionauth.User, req *ht
 	}
 }
 
+/**
+ * Handler to permanently remove all mails of a folder, which have their \Deleted flag set (form
+ * value folder).
+ * @return {"removed": The number of removed mails}
+ */
+func (web *MailWeb) expunge(r render.Render, curUser sessionauth.User, req *http.Request) {
+	var watneyUser *auth.WatneyUser = curUser.(*auth.WatneyUser)
+	if !watneyUser.ImapCon.IsAuthenticated() {
+		web.notifyAuthTimeout(r, "Expunge folder")
+		return
+	}
+	removed, err := watneyUser.ImapCon.Expunge(req.FormValue("folder"))
+	if err != nil {
+		web.notifyError(r, 500, fmt.Sprintf("Folder '%s' couldn't be expunged",
+			req.FormValue("folder")), err.Error())
+		return
+	}
+	r.JSON(200, map[string]int{"removed": removed})
+}
+
+/**
+ * Handler to permanently delete mails without moving them to the Trash folder. Form values:
+ *	- folder: The folder of the mails
+ *	- uids: The UIDs of the mails (repeated or comma separated)
+ *	- confirmed: true, if the user confirmed the deletion (see MailConf.ConfirmDelete)
+ * @return {"removed": The number of removed mails}
+ */
+func (web *MailWeb) deleteMails(r render.Render, curUser sessionauth.User, req *http.Request) {
+	var watneyUser *auth.WatneyUser = curUser.(*auth.WatneyUser)
+	if !watneyUser.ImapCon.IsAuthenticated() {
+		web.notifyAuthTimeout(r, "Delete mails")
+		return
+	}
+	req.ParseForm()
+	uids, err := parseUIDs(req.Form["uids"])
+	if err != nil {
+		web.notifyError(r, 200, "Given UIDs are not valid IDs", err.Error())
+		return
+	}
+	if web.confirmationRequired(r, req,
+		fmt.Sprintf("Delete %d mail(s) permanently?", len(uids))) {
+		return
+	}
+	removed, err := watneyUser.ImapCon.DeleteMails(req.FormValue("folder"), uids)
+	if err != nil {
+		web.notifyError(r, 500, fmt.Sprintf("Mails of folder '%s' couldn't be deleted",
+			req.FormValue("folder")), err.Error())
+		return
+	}
+	r.JSON(200, map[string]int{"removed": removed})
+}
+
+/**
+ * Handler to permanently delete all mails of the Trash or Junk folder. Form values:
+ *	- folder: The folder to be emptied (only the Trash and Junk folder can be emptied)
+ *	- confirmed: true, if the user confirmed the deletion (see MailConf.ConfirmDelete)
+ * @return {"removed": The number of removed mails}
+ */
+func (web *MailWeb) emptyFolder(r render.Render, curUser sessionauth.User, req *http.Request) {
+	var (
+		watneyUser *auth.WatneyUser = curUser.(*auth.WatneyUser)
+		folder     string           = req.FormValue("folder")
+	)
+	if !watneyUser.ImapCon.IsAuthenticated() {
+		web.notifyAuthTimeout(r, "Empty folder")
+		return
+	}
+	if folder != watneyUser.ImapCon.SpecialFolder(mail.SPECIAL_USE_TRASH) &&
+		folder != watneyUser.ImapCon.SpecialFolder(mail.SPECIAL_USE_JUNK) {
+		web.notifyError(r, 200, fmt.Sprintf("Folder '%s' can't be emptied", folder),
+			"Only the Trash and Junk folder can be emptied")
+		return
+	}
+	if web.confirmationRequired(r, req,
+		fmt.Sprintf("Delete all mails of folder '%s' permanently?", folder)) {
+		return
+	}
+	removed, err := watneyUser.ImapCon.EmptyFolder(folder)
+	if err != nil {
+		web.notifyError(r, 500, fmt.Sprintf("Folder '%s' couldn't be emptied", folder),
+			err.Error())
+		return
+	}
+	r.JSON(200, map[string]int{"removed": removed})
+}
+
 func (web *MailWeb) userInfo(r render.Render, curUser sessionauth.User, req *http.Request) {
 	var watneyUser *auth.WatneyUser = curUser.(*auth.WatneyUser)
 	if watneyUser.ImapCon.IsAuthenticated() {
@@ -385,14 +1256,125 @@ func (web *MailWeb) userInfo(r render.Render, curUser sessionauth.User, req *htt
 	}
 }
 
+/**
+ * Handler to retrieve the folder hierarchy of the current user's mailbox.
+ */
+func (web *MailWeb) folders(r render.Render, curUser sessionauth.User, req *http.Request) {
+	var watneyUser *auth.WatneyUser = curUser.(*auth.WatneyUser)
+	if watneyUser.ImapCon.IsAuthenticated() {
+		if folders, err := watneyUser.ImapCon.ListFolders(); err != nil {
+			web.notifyError(r, 500, "Folders couldn't be retrieved", err.Error())
+		} else {
+			r.JSON(200, folders)
+		}
+	} else {
+		web.notifyAuthTimeout(r, "Retrieve folders")
+	}
+}
+
+/**
+ * Handler to retrieve the number of mails, unseen mails and whether new mails have arrived, for
+ * all folders the user subscribed to.
+ */
+func (web *MailWeb) folderStatus(r render.Render, curUser sessionauth.User, req *http.Request) {
+	var watneyUser *auth.WatneyUser = curUser.(*auth.WatneyUser)
+	if watneyUser.ImapCon.IsAuthenticated() {
+		if statuses, err := watneyUser.ImapCon.SubscribedFolderStatus(); err != nil {
+			web.notifyError(r, 500, "Folder status couldn't be retrieved", err.Error())
+		} else {
+			r.JSON(200, statuses)
+		}
+	} else {
+		web.notifyAuthTimeout(r, "Retrieve folder status")
+	}
+}
+
+func (web *MailWeb) createFolder(r render.Render, curUser sessionauth.User, req *http.Request) {
+	var watneyUser *auth.WatneyUser = curUser.(*auth.WatneyUser)
+	if watneyUser.ImapCon.IsAuthenticated() {
+		var (
+			parent string = req.FormValue("parent")
+			name   string = req.FormValue("name")
+		)
+		if folder, err := watneyUser.ImapCon.CreateFolder(parent, name); err != nil {
+			web.notifyError(r, 500, fmt.Sprintf("Folder '%s' couldn't be created", name),
+				err.Error())
+		} else {
+			r.JSON(200, map[string]interface{}{
+				"folder": folder,
+			})
+		}
+	} else {
+		web.notifyAuthTimeout(r, "Create folder")
+	}
+}
+
+func (web *MailWeb) renameFolder(r render.Render, curUser sessionauth.User, req *http.Request) {
+	var watneyUser *auth.WatneyUser = curUser.(*auth.WatneyUser)
+	if watneyUser.ImapCon.IsAuthenticated() {
+		var (
+			folder  string = req.FormValue("folder")
+			newName string = req.FormValue("newName")
+		)
+		if newFolder, err := watneyUser.ImapCon.RenameFolder(folder, newName); err != nil {
+			web.notifyError(r, 500, fmt.Sprintf("Folder '%s' couldn't be renamed to '%s'",
+				folder, newName), err.Error())
+		} else {
+			r.JSON(200, map[string]interface{}{
+				"folder": newFolder,
+			})
+		}
+	} else {
+		web.notifyAuthTimeout(r, "Rename folder")
+	}
+}
+
+func (web *MailWeb) deleteFolder(r render.Render, curUser sessionauth.User, req *http.Request) {
+	var watneyUser *auth.WatneyUser = curUser.(*auth.WatneyUser)
+	if watneyUser.ImapCon.IsAuthenticated() {
+		var folder string = req.FormValue("folder")
+		if err := watneyUser.ImapCon.DeleteFolder(folder); err != nil {
+			web.notifyError(r, 500, fmt.Sprintf("Folder '%s' couldn't be deleted", folder),
+				err.Error())
+		} else {
+			r.JSON(200, nil)
+		}
+	} else {
+		web.notifyAuthTimeout(r, "Delete folder")
+	}
+}
+
+/**
+ * Handler to subscribe (subscribe=true) or unsubscribe (subscribe=false) a folder.
+ */
+func (web *MailWeb) subscribeFolder(r render.Render, curUser sessionauth.User,
+	req *http.Request) {
+	var watneyUser *auth.WatneyUser = curUser.(*auth.WatneyUser)
+	if watneyUser.ImapCon.IsAuthenticated() {
+		var folder string = req.FormValue("folder")
+		subscribe, err := strconv.ParseBool(req.FormValue("subscribe"))
+		if err != nil {
+			web.notifyError(r, 200,
+				fmt.Sprintf("Couldn't parse string '%s' into bool", req.FormValue("subscribe")),
+				err.Error())
+			return
+		}
+		if err = watneyUser.ImapCon.UpdateFolderSubscription(folder, subscribe); err != nil {
+			web.notifyError(r, 500,
+				fmt.Sprintf("Subscription of folder '%s' couldn't be changed", folder), err.Error())
+		} else {
+			r.JSON(200, nil)
+		}
+	} else {
+		web.notifyAuthTimeout(r, "Change folder subscription")
+	}
+}
+
 func (web *MailWeb) updateFlags(r render.Render, curUser sessionauth.User, req *http.Request) {
 	var (
 		watneyUser *auth.WatneyUser = curUser.(*auth.WatneyUser)
 		folder     string
 		uid        string
-		addFlags   bool
-		flags      mail.Flags
-		err        error
 	)
 	if watneyUser.ImapCon.IsAuthenticated() {
 		// 1) Get the folder
@@ -405,20 +1387,12 @@ func (web *MailWeb) updateFlags(r render.Render, curUser sessionauth.User, req *
 		} else {
 			uid = req.FormValue("uid")
 		}
-		// 3) Check the add/remove form value
-		if addFlags, err = strconv.ParseBool(req.FormValue("add")); err != nil {
-			web.notifyError(r, 200,
-				fmt.Sprintf("Couldn't parse string '%s' into bool", req.FormValue("add")),
-				err.Error())
+		// 3) Check the add/remove form value and the flags
+		flags, addFlags, ok := web.readFlagUpdate(r, req)
+		if !ok {
 			return
 		}
-		// 4) Check the flags
-		if err = json.Unmarshal([]byte(req.FormValue("flags")), &flags); err != nil {
-			fmt.Println("error:", err)
-			r.Error(500)
-			return
-		}
-		if err = watneyUser.ImapCon.UpdateMailFlags(folder, uid, &flags, addFlags); err != nil {
+		if err := watneyUser.ImapCon.UpdateMailFlags(folder, uid, flags, addFlags); err != nil {
 			web.notifyError(r, 500, fmt.Sprintf("Error while performing UpdateMailFlags"), err.Error())
 		} else {
 			r.Status(200)
@@ -428,9 +1402,157 @@ func (web *MailWeb) updateFlags(r render.Render, curUser sessionauth.User, req *
 	}
 }
 
+/**
+ * Handler to load the keywords (labels), which are defined in a folder (form value folder).
+ * @return {"Keywords": [...], "CanCreate": whether new keywords can be stored in the folder}
+ */
+func (web *MailWeb) keywords(r render.Render, curUser sessionauth.User, req *http.Request) {
+	var watneyUser *auth.WatneyUser = curUser.(*auth.WatneyUser)
+	if !watneyUser.ImapCon.IsAuthenticated() {
+		web.notifyAuthTimeout(r, "Load keywords")
+		return
+	}
+	keywords, err := watneyUser.ImapCon.LoadKeywords(req.FormValue("folder"))
+	if err != nil {
+		web.notifyError(r, 500, fmt.Sprintf("Keywords of folder '%s' couldn't be loaded",
+			req.FormValue("folder")), err.Error())
+		return
+	}
+	r.JSON(200, keywords)
+}
+
+/**
+ * Handler to apply an operation to several mails of a folder at once. Form values:
+ *	- operation: move | trash | flags
+ *	- folder: The folder of the mails
+ *	- uids: The UIDs of the mails (repeated or comma separated)
+ *	- targetFolder: The folder the mails are moved to (only for move)
+ *	- flags, add: The flags to be set or removed (only for flags, see updateFlags)
+ * @return The result of each mail: [{"UID": uid, "NewUID": uid after a move, "Error": reason}]
+ */
+func (web *MailWeb) bulk(r render.Render, curUser sessionauth.User, req *http.Request) {
+	var (
+		watneyUser *auth.WatneyUser = curUser.(*auth.WatneyUser)
+		folder     string           = req.FormValue("folder")
+		operation  string           = req.FormValue("operation")
+		results    []mail.BulkResult
+	)
+	if !watneyUser.ImapCon.IsAuthenticated() {
+		web.notifyAuthTimeout(r, fmt.Sprintf("Bulk %s", operation))
+		return
+	}
+	uids, err := parseUIDs(req.Form["uids"])
+	if err != nil {
+		web.notifyError(r, 200, "Given UIDs are not valid IDs", err.Error())
+		return
+	}
+	switch operation {
+	case "move":
+		results, err = watneyUser.ImapCon.MoveMails(uids, folder, req.FormValue("targetFolder"))
+	case "trash":
+		results, err = watneyUser.ImapCon.TrashMails(uids, folder)
+	case "flags":
+		flags, addFlags, ok := web.readFlagUpdate(r, req)
+		if !ok {
+			return
+		}
+		results, err = watneyUser.ImapCon.UpdateMailsFlags(folder, uids, flags, addFlags)
+	default:
+		web.notifyError(r, 200, fmt.Sprintf("Unknown bulk operation '%s'", operation),
+			"Supported operations are: move, trash, flags")
+		return
+	}
+	// Failures of single mails are part of the results
+	if err != nil && 0 == len(results) {
+		web.notifyError(r, 500, fmt.Sprintf("Bulk %s in folder '%s' failed", operation, folder),
+			err.Error())
+		return
+	}
+	r.JSON(200, results)
+}
+
+/**
+ * Reads the form values add (true = set, false = remove the flags) and flags (JSON encoded Flags,
+ * custom labels are given as Keywords, e.g., {"Keywords": ["$label1"]}).
+ * @return False, if one of them is invalid (the error has been written to the response already)
+ */
+func (web *MailWeb) readFlagUpdate(r render.Render, req *http.Request) (*mail.Flags, bool, bool) {
+	var flags mail.Flags
+	addFlags, err := strconv.ParseBool(req.FormValue("add"))
+	if err != nil {
+		web.notifyError(r, 200,
+			fmt.Sprintf("Couldn't parse string '%s' into bool", req.FormValue("add")),
+			err.Error())
+		return nil, false, false
+	}
+	if err = json.Unmarshal([]byte(req.FormValue("flags")), &flags); err != nil {
+		fmt.Println("error:", err)
+		r.Error(500)
+		return nil, false, false
+	}
+	return &flags, addFlags, true
+}
+
 /**************************************************************************************************
  ***							Web return notifications									    ***
  **************************************************************************************************/
+/**
+ * Checks whether the user has to confirm a permanent deletion first (see MailConf.ConfirmDelete).
+ * If so, the JSON response {"confirm": question} is written and the deletion must not be
+ * performed. The deletion has to be requested again with the form value confirmed=true.
+ * @return True, if the confirmation is still missing
+ */
+func (web *MailWeb) confirmationRequired(r render.Render, req *http.Request,
+	question string) bool {
+	if !web.mconf.ConfirmDelete || "true" == req.FormValue("confirmed") {
+		return false
+	}
+	r.JSON(200, map[string]string{"confirm": question})
+	return true
+}
+
+/**
+ * Deletes all mails of the Trash and Junk folder, which are older than the configured retention
+ * period (see MailConf.TrashRetentionDays).
+ */
+func (web *MailWeb) applyRetention(imapCon *mail.MailCon) {
+	if web.mconf.TrashRetentionDays <= 0 {
+		return
+	}
+	var before time.Time = time.Now().AddDate(0, 0, -web.mconf.TrashRetentionDays)
+	for _, specialUse := range []string{mail.SPECIAL_USE_TRASH, mail.SPECIAL_USE_JUNK} {
+		folder := imapCon.SpecialFolder(specialUse)
+		if removed, err := imapCon.PurgeFolder(folder, before); err != nil {
+			fmt.Printf("[watney] WARNING: Couldn't purge folder '%s': %s\n", folder, err.Error())
+		} else if removed > 0 {
+			fmt.Printf("[watney] Purged %d mail(s) of folder '%s'\n", removed, folder)
+		}
+	}
+}
+
+/**
+ * Parses the given UIDs, which can be given as repeated or comma separated form values.
+ */
+func parseUIDs(values []string) ([]uint32, error) {
+	var uids []uint32 = []uint32{}
+	for _, value := range values {
+		for _, entry := range strings.Split(value, ",") {
+			if entry = strings.TrimSpace(entry); 0 == len(entry) {
+				continue
+			}
+			uid, err := strconv.ParseUint(entry, 10, 32)
+			if err != nil {
+				return uids, fmt.Errorf("Given UID '%s' is not a valid ID", entry)
+			}
+			uids = append(uids, uint32(uid))
+		}
+	}
+	if 0 == len(uids) {
+		return uids, errors.New("No UIDs given")
+	}
+	return uids, nil
+}
+
 /**
  * Logs that the session has timed out while the user tried to perform the 'origAction' and
  * writes an error to the JSON render response.